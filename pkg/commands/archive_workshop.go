@@ -0,0 +1,292 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+//ArchiveWorkshopOptions the options to archive the participant repos
+type ArchiveWorkshopOptions struct {
+	configFile string
+	outputDir  string
+	format     string
+}
+
+//ArchiveManifest describes the archived participant repos
+type ArchiveManifest struct {
+	GiteaURL string         `yaml:"giteaURL"`
+	Created  time.Time      `yaml:"created"`
+	Repos    []ArchivedRepo `yaml:"repos"`
+}
+
+//ArchivedRepo is a participant repo that was archived
+type ArchivedRepo struct {
+	User    string           `yaml:"user"`
+	Repo    string           `yaml:"repo"`
+	Archive string           `yaml:"archive,omitempty"`
+	Ref     string           `yaml:"ref,omitempty"`
+	Authors []string         `yaml:"authors,omitempty"`
+	Commits []ArchivedCommit `yaml:"commits,omitempty"`
+}
+
+//ArchivedCommit is a commit of the archived repo
+type ArchivedCommit struct {
+	SHA     string `yaml:"sha"`
+	Author  string `yaml:"author"`
+	Email   string `yaml:"email"`
+	Date    string `yaml:"date"`
+	Message string `yaml:"message"`
+}
+
+// ArchiveWorkshopOptions implements Interface
+var _ Command = (*ArchiveWorkshopOptions)(nil)
+
+var archiveWorkshopCommandExample = fmt.Sprintf(`
+  # Download the participant repos as tar.gz in to the directory 'archives'
+  %[1]s archive-workshop --workshop-file workshop.yaml
+  # Download the participant repos as zip in to the directory 'submissions'
+  %[1]s archive-workshop -f workshop.yaml --output-dir submissions --format zip
+`, ExamplePrefix())
+
+//NewArchiveWorkshopCommand instantiates the new instance of the ArchiveWorkshopCommand
+func NewArchiveWorkshopCommand() *cobra.Command {
+	archiveOpts := &ArchiveWorkshopOptions{}
+
+	archiveCmd := &cobra.Command{
+		Use:     "archive-workshop",
+		Short:   "Download the participant repos along with a manifest of their commits",
+		Example: archiveWorkshopCommandExample,
+		RunE:    archiveOpts.Execute,
+		PreRunE: archiveOpts.Validate,
+	}
+
+	archiveOpts.AddFlags(archiveCmd)
+
+	return archiveCmd
+}
+
+// AddFlags implements Command
+func (opts *ArchiveWorkshopOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.outputDir, "output-dir", "d", "archives", "The directory to download the archives to")
+	cmd.Flags().StringVarP(&opts.format, "format", "t", "tar.gz", "The archive format, one of tar.gz or zip")
+}
+
+// Validate implements Command
+func (opts *ArchiveWorkshopOptions) Validate(cmd *cobra.Command, args []string) error {
+	if _, err := archiveType(opts.format); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Execute implements Command
+func (opts *ArchiveWorkshopOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(opts.outputDir, 0755); err != nil {
+		return err
+	}
+
+	manifest := ArchiveManifest{
+		GiteaURL: workshopOpts.GiteaURL,
+		Created:  time.Now().UTC(),
+	}
+
+	giteaUsers := workshopOpts.GiteaUsers
+	templates := make(map[string]map[string]bool)
+	for _, repoURL := range giteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return err
+		}
+		if templates[repoName], err = templateCommits(c, workshopOpts.GiteaAdminUser, repoName); err != nil {
+			return err
+		}
+	}
+
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		userName := participantUserName(i)
+		for _, repoURL := range giteaUsers.Repos {
+			repoName, err := repoNameFromURL(repoURL)
+			if err != nil {
+				return err
+			}
+			archived, err := opts.archiveRepo(c, userName, repoName, templates[repoName])
+			if err != nil {
+				return err
+			}
+			if archived != nil {
+				manifest.Repos = append(manifest.Repos, *archived)
+			}
+		}
+	}
+
+	b, err := yamlv2.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	manifestFile := filepath.Join(opts.outputDir, "manifest.yaml")
+	if err := ioutil.WriteFile(manifestFile, b, 0644); err != nil {
+		return err
+	}
+
+	log.Infof("Archived %d repos, manifest written to %s", len(manifest.Repos), manifestFile)
+
+	return nil
+}
+
+//archiveRepo downloads the archive of the user's repo default branch and returns its manifest entry,
+//nil is returned when the user does not have the repo. The authors of the template commits are not
+//listed as authors of the repo.
+func (opts *ArchiveWorkshopOptions) archiveRepo(c *gitea.Client, user, repoName string, template map[string]bool) (*ArchivedRepo, error) {
+	repo, resp, err := c.GetRepo(user, repoName)
+	if err != nil {
+		if isNotFound(resp) {
+			log.Warnf("Repo %s does not exist for user %s, skipping archive", repoName, user)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	archived := &ArchivedRepo{
+		User: user,
+		Repo: repoName,
+	}
+
+	if repo.Empty {
+		log.Warnf("Repo %s of user %s is empty, nothing to archive", repoName, user)
+		return archived, nil
+	}
+
+	commits, err := listRepoCommits(c, user, repoName, repo.DefaultBranch)
+	if err != nil {
+		return nil, err
+	}
+	archived.Ref = repo.DefaultBranch
+	if len(commits) > 0 {
+		archived.Ref = commits[0].SHA
+	}
+
+	authors := make(map[string]bool)
+	for _, commit := range commits {
+		ac := ArchivedCommit{SHA: commit.SHA}
+		if rc := commit.RepoCommit; rc != nil {
+			ac.Message = strings.TrimSpace(rc.Message)
+			if rc.Author != nil {
+				ac.Author = rc.Author.Name
+				ac.Email = rc.Author.Email
+				ac.Date = rc.Author.Date
+			}
+		}
+		archived.Commits = append(archived.Commits, ac)
+		if !template[commit.SHA] {
+			authors[fmt.Sprintf("%s <%s>", ac.Author, ac.Email)] = true
+		}
+	}
+	for a := range authors {
+		archived.Authors = append(archived.Authors, a)
+	}
+	sort.Strings(archived.Authors)
+
+	ext, _ := archiveType(opts.format)
+	r, _, err := c.GetArchiveReader(user, repoName, archived.Ref, ext)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	archived.Archive = fmt.Sprintf("%s-%s%s", user, repoName, ext)
+	f, err := os.Create(filepath.Join(opts.outputDir, archived.Archive))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Archived repo %s of user %s to %s", repoName, user, f.Name())
+
+	return archived, nil
+}
+
+//templateCommits returns the commits of the admin user's copy of the template repo, the history
+//the participant repos were migrated from
+func templateCommits(c *gitea.Client, owner, repoName string) (map[string]bool, error) {
+	commits := make(map[string]bool)
+	repo, resp, err := c.GetRepo(owner, repoName)
+	if err != nil {
+		if isNotFound(resp) {
+			log.Warnf("Template repo %s does not exist for user %s, the template commit authors will be listed", repoName, owner)
+			return commits, nil
+		}
+		return nil, err
+	}
+	if repo.Empty {
+		return commits, nil
+	}
+	history, err := listRepoCommits(c, owner, repoName, repo.DefaultBranch)
+	if err != nil {
+		return nil, err
+	}
+	for _, commit := range history {
+		commits[commit.SHA] = true
+	}
+	return commits, nil
+}
+
+//listRepoCommits lists all the commits of the repo starting from the ref, newest first
+func listRepoCommits(c *gitea.Client, user, repoName, ref string) ([]*gitea.Commit, error) {
+	var commits []*gitea.Commit
+	opt := gitea.ListCommitOptions{
+		ListOptions: gitea.ListOptions{Page: 1, PageSize: 50},
+		SHA:         ref,
+	}
+	for {
+		page, _, err := c.ListRepoCommits(user, repoName, opt)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, page...)
+		if len(page) < opt.PageSize {
+			break
+		}
+		opt.Page++
+	}
+	return commits, nil
+}
+
+//archiveType maps the archive format to the Gitea archive type
+func archiveType(format string) (gitea.ArchiveType, error) {
+	switch strings.TrimPrefix(format, ".") {
+	case "tar.gz", "tgz":
+		return gitea.TarGZArchive, nil
+	case "zip":
+		return gitea.ZipArchive, nil
+	}
+	return "", fmt.Errorf("unsupported archive format %q, must be one of tar.gz or zip", format)
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+func TestArchiveWorkshop(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/repos/demo/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","default_branch":"main"}`)
+		},
+		"/api/v1/repos/demo/jar-stack/commits": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"sha":"c1","commit":{"message":"Initial commit","author":{"name":"Kamesh","email":"kamesh@example.com","date":"2022-08-01T10:00:00Z"}}}]`)
+		},
+		"/api/v1/repos/user-01/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","default_branch":"main"}`)
+		},
+		"/api/v1/repos/user-01/jar-stack/commits": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[
+{"sha":"c2","commit":{"message":"Fix the pipeline\n","author":{"name":"User 01","email":"user-01@example.com","date":"2022-08-02T10:00:00Z"}}},
+{"sha":"c1","commit":{"message":"Initial commit","author":{"name":"Kamesh","email":"kamesh@example.com","date":"2022-08-01T10:00:00Z"}}}
+]`)
+		},
+		"/api/v1/repos/user-01/jar-stack/archive/c2.zip": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "zip")
+		},
+		"/api/v1/repos/user-02/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+	})
+	outputDir := t.TempDir()
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"archive-workshop", "-f", writeWorkshopFile(t, s.URL), "-d", outputDir, "-t", "zip", "-v", "debug"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(outputDir, "user-01-jar-stack.zip"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if string(b) != "zip" {
+		t.Errorf("Expecting archive content 'zip' but got %s", b)
+	}
+
+	var manifest ArchiveManifest
+	b, err = ioutil.ReadFile(filepath.Join(outputDir, "manifest.yaml"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := yamlv2.Unmarshal(b, &manifest); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := []ArchivedRepo{
+		{
+			User:    "user-01",
+			Repo:    "jar-stack",
+			Archive: "user-01-jar-stack.zip",
+			Ref:     "c2",
+			//the author of the template commit c1 is not an author of the repo
+			Authors: []string{"User 01 <user-01@example.com>"},
+			Commits: []ArchivedCommit{
+				{SHA: "c2", Author: "User 01", Email: "user-01@example.com", Date: "2022-08-02T10:00:00Z", Message: "Fix the pipeline"},
+				{SHA: "c1", Author: "Kamesh", Email: "kamesh@example.com", Date: "2022-08-01T10:00:00Z", Message: "Initial commit"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(expected, manifest.Repos) {
+		t.Errorf("Expecting manifest repos %v but got %v", expected, manifest.Repos)
+	}
+}
+
+func TestArchiveType(t *testing.T) {
+	for format, expected := range map[string]gitea.ArchiveType{
+		"tar.gz": gitea.TarGZArchive,
+		"tgz":    gitea.TarGZArchive,
+		".zip":   gitea.ZipArchive,
+	} {
+		actual, err := archiveType(format)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if actual != expected {
+			t.Errorf("Expecting archive type %s for %s but got %s", expected, format, actual)
+		}
+	}
+
+	if _, err := archiveType("rar"); err == nil {
+		t.Error("Expecting error for unsupported archive format rar")
+	}
+}
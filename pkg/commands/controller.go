@@ -0,0 +1,290 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+//ControllerOptions the options to run the controller reconciling the Workshop custom resources
+type ControllerOptions struct {
+	kubeconfig       string
+	namespace        string
+	resync           time.Duration
+	secretNamespaces []string
+}
+
+// ControllerOptions implements Interface
+var _ Command = (*ControllerOptions)(nil)
+
+var controllerCommandExample = fmt.Sprintf(`
+  # Reconcile the Workshops of all the namespaces
+  %[1]s controller -k ~/.kube/config
+  # Reconcile the Workshops of the namespace drone every 5 minutes
+  %[1]s controller --namespace drone --resync 5m
+  # Allow the Workshops to write the Argo CD repository secrets to the namespace argocd
+  %[1]s controller --secret-namespaces argocd
+`, ExamplePrefix())
+
+//NewControllerCommand instantiates the new instance of the ControllerCommand
+func NewControllerCommand() *cobra.Command {
+	controllerOpts := &ControllerOptions{}
+
+	controllerCmd := &cobra.Command{
+		Use:     "controller",
+		Short:   "Reconcile the participants of the Workshop custom resources",
+		Example: controllerCommandExample,
+		RunE:    controllerOpts.Execute,
+		PreRunE: controllerOpts.Validate,
+	}
+
+	controllerOpts.AddFlags(controllerCmd)
+
+	return controllerCmd
+}
+
+// AddFlags implements Command
+func (opts *ControllerOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.kubeconfig, "kubeconfig", "k", "", "The kubeconfig file to use")
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", metav1.NamespaceAll, "The namespace of the Workshops to reconcile, defaults to all the namespaces")
+	cmd.Flags().DurationVar(&opts.resync, "resync", 10*time.Minute, "How often the Workshops are reconciled to heal the drift")
+	cmd.Flags().StringSliceVar(&opts.secretNamespaces, "secret-namespaces", nil, "The namespaces, besides the namespace of the Workshop and the participant namespaces, the Workshops may write their secrets to")
+}
+
+// Validate implements Command
+func (opts *ControllerOptions) Validate(cmd *cobra.Command, args []string) error {
+	if opts.resync < time.Minute {
+		return fmt.Errorf("the resync period must be at least 1m but is %s", opts.resync)
+	}
+	return nil
+}
+
+// Execute implements Command
+func (opts *ControllerOptions) Execute(cmd *cobra.Command, args []string) error {
+	config, err := newKubernetesConfig(opts.kubeconfig)
+	if err != nil {
+		return err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return newWorkshopController(client, opts.namespace, opts.resync, opts.kubeconfig, opts.secretNamespaces).run(ctx)
+}
+
+//workshopController reconciles the Workshops one at a time as provisioning a workshop is sequential
+type workshopController struct {
+	client     dynamic.Interface
+	informer   cache.SharedIndexInformer
+	queue      workqueue.RateLimitingInterface
+	kubeconfig string
+	//secretNamespaces are the namespaces, besides the ones of the Workshop and of the participants, of the secrets
+	secretNamespaces []string
+}
+
+//newWorkshopController returns the controller of the Workshops of the namespace
+func newWorkshopController(client dynamic.Interface, namespace string, resync time.Duration, kubeconfig string, secretNamespaces []string) *workshopController {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, resync, namespace, nil)
+	wc := &workshopController{
+		client:     client,
+		informer:   factory.ForResource(workshopGVR).Informer(),
+		queue:      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		kubeconfig: kubeconfig,
+
+		secretNamespaces: secretNamespaces,
+	}
+
+	enqueue := func(obj interface{}) {
+		if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+			wc.queue.Add(key)
+		}
+	}
+	wc.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldWorkshop, workshop := oldObj.(*unstructured.Unstructured), newObj.(*unstructured.Unstructured)
+			//the resyncs heal the drift, the status updates of the controller are skipped
+			if oldWorkshop.GetResourceVersion() == workshop.GetResourceVersion() ||
+				oldWorkshop.GetGeneration() != workshop.GetGeneration() || workshop.GetDeletionTimestamp() != nil {
+				enqueue(newObj)
+			}
+		},
+	})
+
+	return wc
+}
+
+//run reconciles the Workshops until the context is done
+func (wc *workshopController) run(ctx context.Context) error {
+	defer wc.queue.ShutDown()
+
+	go wc.informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), wc.informer.HasSynced) {
+		return fmt.Errorf("error syncing the Workshops")
+	}
+	log.Infof("Reconciling the Workshops")
+
+	go func() {
+		<-ctx.Done()
+		wc.queue.ShutDown()
+	}()
+	for wc.processNextItem(ctx) {
+	}
+	return nil
+}
+
+//processNextItem reconciles the next Workshop of the queue, false when the queue is shut down
+func (wc *workshopController) processNextItem(ctx context.Context) bool {
+	key, quit := wc.queue.Get()
+	if quit {
+		return false
+	}
+	defer wc.queue.Done(key)
+
+	obj, exists, err := wc.informer.GetIndexer().GetByKey(key.(string))
+	if err == nil && exists {
+		err = wc.reconcile(ctx, obj.(*unstructured.Unstructured).DeepCopy())
+	}
+	if err != nil {
+		log.Errorf("Error reconciling Workshop %s, %v", key, err)
+		wc.queue.AddRateLimited(key)
+		return true
+	}
+	wc.queue.Forget(key)
+	return true
+}
+
+//reconcile provisions the participants of the Workshop and reports their status, or tears them down
+//when the Workshop is deleted
+func (wc *workshopController) reconcile(ctx context.Context, u *unstructured.Unstructured) error {
+	workshops := wc.client.Resource(workshopGVR).Namespace(u.GetNamespace())
+
+	if u.GetDeletionTimestamp() != nil {
+		if !hasFinalizer(u) {
+			return nil
+		}
+		status, err := workshopStatus(u)
+		if err != nil {
+			return err
+		}
+		var remaining []WorkshopParticipantStatus
+		workshopOpts, teardownErr := wc.workshopSpec(u)
+		if teardownErr == nil {
+			remaining, teardownErr = workshopOpts.teardown(wc.kubeconfig, status.Participants)
+		} else if len(status.Participants) == 0 {
+			//nothing was provisioned with the invalid spec
+			log.Warnf("Skipping the teardown of Workshop %s/%s with the invalid spec, %v", u.GetNamespace(), u.GetName(), teardownErr)
+			teardownErr = nil
+		}
+		if teardownErr != nil {
+			if !forceTeardown(u) {
+				if remaining != nil {
+					status.Participants = remaining
+				}
+				status.setReady(false, "TeardownFailed", fmt.Sprintf("%v, annotate the Workshop with %s=true to remove it anyway", teardownErr, workshopForceTeardown), u.GetGeneration())
+				if err := wc.updateStatus(ctx, u, status); err != nil {
+					return err
+				}
+				return teardownErr
+			}
+			log.Warnf("Forcing the removal of Workshop %s/%s, the participants %v are left behind, %v", u.GetNamespace(), u.GetName(), remaining, teardownErr)
+		}
+		removeFinalizer(u)
+		_, err = workshops.Update(ctx, u, metav1.UpdateOptions{})
+		return err
+	}
+
+	if !hasFinalizer(u) {
+		u.SetFinalizers(append(u.GetFinalizers(), workshopFinalizer))
+		updated, err := workshops.Update(ctx, u, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		u = updated
+	}
+
+	status, err := workshopStatus(u)
+	if err != nil {
+		return err
+	}
+
+	workshopOpts, err := wc.workshopSpec(u)
+	if err != nil {
+		//the spec is not retried until it changes or the resync e.g. once the admin password secret is created
+		status.setReady(false, "InvalidSpec", err.Error(), u.GetGeneration())
+		return wc.updateStatus(ctx, u, status)
+	}
+
+	//the participants to provision are recorded before their Gitea users are created
+	claimed, claimErr := workshopOpts.claimParticipants(status.Participants)
+	if claimErr != nil {
+		status.setReady(false, "ReconcileFailed", claimErr.Error(), u.GetGeneration())
+		if err := wc.updateStatus(ctx, u, status); err != nil {
+			return err
+		}
+		return claimErr
+	}
+	status.Participants = claimed
+	if err := wc.updateStatus(ctx, u, status); err != nil {
+		return err
+	}
+
+	participants, reconcileErr := workshopOpts.reconcileParticipants(wc.kubeconfig, status.Participants)
+	status.Participants = participants
+	if reconcileErr != nil {
+		status.setReady(false, "ReconcileFailed", reconcileErr.Error(), u.GetGeneration())
+	} else {
+		status.setReady(true, "Reconciled", fmt.Sprintf("%d participants are ready", len(participants)), u.GetGeneration())
+	}
+	if err := wc.updateStatus(ctx, u, status); err != nil {
+		return err
+	}
+	return reconcileErr
+}
+
+//workshopSpec returns the workshop configuration of the spec of the Workshop
+func (wc *workshopController) workshopSpec(u *unstructured.Unstructured) (*WorkshopOptions, error) {
+	clientset, err := newKubernetesClient(wc.kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	workshopOpts, err := workshopSpec(clientset, u)
+	if err != nil {
+		return nil, err
+	}
+	if err := workshopOpts.validateSecretNamespaces(u.GetNamespace(), wc.secretNamespaces); err != nil {
+		return nil, err
+	}
+	return workshopOpts, nil
+}
+
+//updateStatus updates the status of the Workshop when it changed
+func (wc *workshopController) updateStatus(ctx context.Context, u *unstructured.Unstructured, status *WorkshopStatus) error {
+	changed, err := setWorkshopStatus(u, status)
+	if err != nil || !changed {
+		return err
+	}
+	updated, err := wc.client.Resource(workshopGVR).Namespace(u.GetNamespace()).UpdateStatus(ctx, u, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	//the next update of the Workshop must not conflict with this one
+	u.SetResourceVersion(updated.GetResourceVersion())
+	return nil
+}
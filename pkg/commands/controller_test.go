@@ -0,0 +1,425 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+//newWorkshop returns the Workshop custom resource with the spec of the workshop file
+func newWorkshop(t *testing.T, workshopFile string) *unstructured.Unstructured {
+	b, err := ioutil.ReadFile(workshopFile)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(b, &spec); err != nil {
+		t.Fatalf("%v", err)
+	}
+	//the admin password is read from a secret of the namespace of the Workshop
+	useFakeKubernetes(t, &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gitea-admin", Namespace: "drone"},
+		Data:       map[string][]byte{"password": []byte(fmt.Sprint(spec["giteaAdminUserPassword"]))},
+	})
+	delete(spec, "giteaAdminUserPassword")
+	spec["giteaAdminUserPasswordSecretRef"] = map[string]interface{}{"name": "gitea-admin"}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetAPIVersion(workshopGVR.GroupVersion().String())
+	u.SetKind("Workshop")
+	u.SetNamespace("drone")
+	u.SetName("tutorial")
+	u.SetGeneration(1)
+	return u
+}
+
+//newFakeWorkshopController returns the controller of the fake cluster with the Workshop
+func newFakeWorkshopController(u *unstructured.Unstructured) *workshopController {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{workshopGVR: "WorkshopList"}, u)
+	return &workshopController{client: client}
+}
+
+//fakeGiteaUsers is a stand-in of the Gitea users and repos API recording the deletions
+type fakeGiteaUsers struct {
+	sync.Mutex
+	//repos are the repos of each user
+	repos   map[string][]string
+	deleted []string
+	//deleteUserStatus is the status of the user deletions, 204 when not set
+	deleteUserStatus int
+}
+
+func newFakeGiteaUsers(t *testing.T, users ...string) (*fakeGiteaUsers, string) {
+	g := &fakeGiteaUsers{repos: make(map[string][]string)}
+	for _, u := range users {
+		g.repos[u] = nil
+	}
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/admin/users": func(w http.ResponseWriter, r *http.Request) {
+			var u struct {
+				UserName string `json:"username"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+				t.Errorf("%v", err)
+			}
+			g.Lock()
+			g.repos[u.UserName] = nil
+			g.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"login":%q}`, u.UserName)
+		},
+		"/api/v1/admin/users/": func(w http.ResponseWriter, r *http.Request) {
+			user := path.Base(r.URL.Path)
+			g.Lock()
+			defer g.Unlock()
+			if g.deleteUserStatus != 0 {
+				w.WriteHeader(g.deleteUserStatus)
+				fmt.Fprint(w, `{"message":"user still owns one or more repositories"}`)
+				return
+			}
+			delete(g.repos, user)
+			g.deleted = append(g.deleted, user)
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"/api/v1/users/": func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/users/"), "/")
+			g.Lock()
+			defer g.Unlock()
+			repos, ok := g.repos[parts[0]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			if len(parts) == 1 {
+				fmt.Fprintf(w, `{"login":%q}`, parts[0])
+				return
+			}
+			var list []string
+			if r.URL.Query().Get("page") == "1" {
+				for _, name := range repos {
+					list = append(list, fmt.Sprintf(`{"name":%q,"owner":{"login":%q}}`, name, parts[0]))
+				}
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(list, ","))
+		},
+		"/api/v1/repos/migrate": func(w http.ResponseWriter, r *http.Request) {
+			var m gitea.MigrateRepoOption
+			if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+				t.Errorf("%v", err)
+			}
+			g.Lock()
+			g.repos[m.RepoOwner] = append(g.repos[m.RepoOwner], m.RepoName)
+			g.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"name":%q,"clone_url":"http://gitea.example.com/%s/%s.git"}`, m.RepoName, m.RepoOwner, m.RepoName)
+		},
+		"/api/v1/repos/": func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/repos/"), "/")
+			g.Lock()
+			defer g.Unlock()
+			for i, name := range g.repos[parts[0]] {
+				if name != parts[1] {
+					continue
+				}
+				if r.Method == http.MethodDelete {
+					g.repos[parts[0]] = append(g.repos[parts[0]][:i], g.repos[parts[0]][i+1:]...)
+					g.deleted = append(g.deleted, parts[0]+"/"+name)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				fmt.Fprintf(w, `{"name":%q,"clone_url":"http://gitea.example.com/%s/%s.git"}`, name, parts[0], name)
+				return
+			}
+			http.NotFound(w, r)
+		},
+	})
+	return g, s.URL
+}
+
+//reconcileWorkshop reconciles the Workshop and returns it with its status
+func reconcileWorkshop(t *testing.T, wc *workshopController, u *unstructured.Unstructured) (*unstructured.Unstructured, *WorkshopStatus, error) {
+	err := wc.reconcile(context.TODO(), u)
+	u, getErr := wc.client.Resource(workshopGVR).Namespace("drone").Get(context.TODO(), "tutorial", metav1.GetOptions{})
+	if getErr != nil {
+		t.Fatalf("%v", getErr)
+	}
+	status, statusErr := workshopStatus(u)
+	if statusErr != nil {
+		t.Fatalf("%v", statusErr)
+	}
+	return u, status, err
+}
+
+//setWorkshopTo sets the last participant of the Workshop
+func setWorkshopTo(t *testing.T, u *unstructured.Unstructured, to int64) {
+	if err := unstructured.SetNestedField(u.Object, to, "spec", "users", "to"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	u.SetGeneration(u.GetGeneration() + 1)
+}
+
+func TestReconcileWorkshop(t *testing.T) {
+	g, giteaURL := newFakeGiteaUsers(t)
+	wc := newFakeWorkshopController(newWorkshop(t, writeWorkshopFile(t, giteaURL)))
+	workshops := wc.client.Resource(workshopGVR).Namespace("drone")
+
+	u, _ := workshops.Get(context.TODO(), "tutorial", metav1.GetOptions{})
+	u, status, err := reconcileWorkshop(t, wc, u)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !hasFinalizer(u) {
+		t.Errorf("Expecting the Workshop to have the finalizer %s", workshopFinalizer)
+	}
+	if !meta.IsStatusConditionTrue(status.Conditions, workshopReady) || status.ObservedGeneration != 1 {
+		t.Errorf("Expecting the Workshop to be ready but got %v", status)
+	}
+	expected := []WorkshopParticipantStatus{{
+		UserName:    "user-01",
+		Ready:       true,
+		Provisioned: true,
+		Repos:       []string{"http://gitea.example.com/user-01/jar-stack.git"},
+	}, {
+		UserName:    "user-02",
+		Ready:       true,
+		Provisioned: true,
+		Repos:       []string{"http://gitea.example.com/user-02/jar-stack.git"},
+	}}
+	if !reflect.DeepEqual(expected, status.Participants) {
+		t.Errorf("Expecting the participants %v but got %v", expected, status.Participants)
+	}
+
+	//the participant removed from the workshop is torn down
+	setWorkshopTo(t, u, 1)
+	u, status, err = reconcileWorkshop(t, wc, u)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if expected := []string{"user-02/jar-stack", "user-02"}; !reflect.DeepEqual(expected, g.deleted) {
+		t.Errorf("Expecting the removed participant to be deleted but got %v", g.deleted)
+	}
+	if len(status.Participants) != 1 || status.Participants[0].UserName != "user-01" {
+		t.Errorf("Expecting only user-01 to be recorded but got %v", status.Participants)
+	}
+
+	//the participant created a repo besides the template copy
+	g.repos["user-01"] = append(g.repos["user-01"], "my-app")
+	g.deleted = nil
+	now := metav1.Now()
+	u.SetDeletionTimestamp(&now)
+	if u, _, err = reconcileWorkshop(t, wc, u); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if expected := []string{"user-01/jar-stack", "user-01/my-app", "user-01"}; !reflect.DeepEqual(expected, g.deleted) {
+		t.Errorf("Expecting the repos and the user to be deleted but got %v", g.deleted)
+	}
+	if hasFinalizer(u) {
+		t.Errorf("Expecting the finalizer to be removed after the teardown")
+	}
+}
+
+func TestReconcileWorkshopExistingUser(t *testing.T) {
+	//user-01 is a participant of another workshop
+	g, giteaURL := newFakeGiteaUsers(t, "user-01")
+	wc := newFakeWorkshopController(newWorkshop(t, writeWorkshopFile(t, giteaURL)))
+	workshops := wc.client.Resource(workshopGVR).Namespace("drone")
+
+	u, _ := workshops.Get(context.TODO(), "tutorial", metav1.GetOptions{})
+	u, status, err := reconcileWorkshop(t, wc, u)
+	if err == nil {
+		t.Errorf("Expecting the existing user not to be adopted")
+	}
+	if p := status.Participants[0]; p.UserName != "user-01" || p.Ready || p.Provisioned {
+		t.Errorf("Expecting the existing user-01 not to be provisioned by the workshop but got %v", p)
+	}
+	if p := status.Participants[1]; p.UserName != "user-02" || !p.Ready || !p.Provisioned {
+		t.Errorf("Expecting user-02 to be provisioned but got %v", p)
+	}
+
+	now := metav1.Now()
+	u.SetDeletionTimestamp(&now)
+	if _, _, err = reconcileWorkshop(t, wc, u); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if expected := []string{"user-02/jar-stack", "user-02"}; !reflect.DeepEqual(expected, g.deleted) {
+		t.Errorf("Expecting only the participant provisioned by the workshop to be deleted but got %v", g.deleted)
+	}
+}
+
+func TestReconcileWorkshopStatusLost(t *testing.T) {
+	_, giteaURL := newFakeGiteaUsers(t)
+	wc := newFakeWorkshopController(newWorkshop(t, writeWorkshopFile(t, giteaURL)))
+	workshops := wc.client.Resource(workshopGVR).Namespace("drone")
+
+	//the status update after the Gitea users are created conflicts
+	updates := 0
+	wc.client.(*dynamicfake.FakeDynamicClient).PrependReactor("update", "workshops", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" {
+			return false, nil, nil
+		}
+		updates++
+		if updates == 2 {
+			return true, nil, apierrors.NewConflict(workshopGVR.GroupResource(), "tutorial", fmt.Errorf("the object has been modified"))
+		}
+		return false, nil, nil
+	})
+
+	u, _ := workshops.Get(context.TODO(), "tutorial", metav1.GetOptions{})
+	u, status, err := reconcileWorkshop(t, wc, u)
+	if err == nil {
+		t.Errorf("Expecting the conflicting status update to be retried")
+	}
+	for _, p := range status.Participants {
+		if !p.Provisioned {
+			t.Errorf("Expecting the participants to be claimed before their Gitea users are created but got %v", p)
+		}
+	}
+
+	if _, status, err = reconcileWorkshop(t, wc, u); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, p := range status.Participants {
+		if !p.Ready || !p.Provisioned {
+			t.Errorf("Expecting the participants created by the workshop to be owned by it but got %v", p)
+		}
+	}
+}
+
+func TestWorkshopSecretNamespaces(t *testing.T) {
+	u := newWorkshop(t, writeWorkshopFile(t, "http://gitea-127.0.0.1.sslip.io:30950", func(o *WorkshopOptions) {
+		o.GiteaUsers.ArgoCDRepoSecrets = &ArgoCDRepoSecrets{}
+	}))
+	wc := newFakeWorkshopController(u)
+	if _, err := wc.workshopSpec(u); err == nil || !strings.Contains(err.Error(), "argocd") {
+		t.Errorf("Expecting the Argo CD secrets not to be written to a namespace the controller does not allow but got %v", err)
+	}
+
+	wc.secretNamespaces = []string{"argocd"}
+	if _, err := wc.workshopSpec(u); err != nil {
+		t.Errorf("Expecting the secrets to be written to the allowed namespace argocd but got %v", err)
+	}
+}
+
+func TestReconcileWorkshopTeardownRefused(t *testing.T) {
+	g, giteaURL := newFakeGiteaUsers(t)
+	wc := newFakeWorkshopController(newWorkshop(t, writeWorkshopFile(t, giteaURL, func(o *WorkshopOptions) {
+		o.GiteaUsers.To = 1
+	})))
+	workshops := wc.client.Resource(workshopGVR).Namespace("drone")
+
+	u, _ := workshops.Get(context.TODO(), "tutorial", metav1.GetOptions{})
+	u, _, err := reconcileWorkshop(t, wc, u)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	//e.g. the user is the owner of an organization
+	g.deleteUserStatus = http.StatusUnprocessableEntity
+	now := metav1.Now()
+	u.SetDeletionTimestamp(&now)
+	u, status, err := reconcileWorkshop(t, wc, u)
+	if err == nil {
+		t.Errorf("Expecting the refused teardown to be retried")
+	}
+	if !hasFinalizer(u) {
+		t.Errorf("Expecting the finalizer to be kept while the participant is not torn down")
+	}
+	c := meta.FindStatusCondition(status.Conditions, workshopReady)
+	if c == nil || c.Reason != "TeardownFailed" || !strings.Contains(c.Message, workshopForceTeardown) {
+		t.Errorf("Expecting the failed teardown to be reported but got %v", c)
+	}
+	if len(status.Participants) != 1 || status.Participants[0].Ready {
+		t.Errorf("Expecting the participant left to be reported but got %v", status.Participants)
+	}
+
+	u.SetAnnotations(map[string]string{workshopForceTeardown: "true"})
+	u.SetDeletionTimestamp(&now)
+	if u, _, err = reconcileWorkshop(t, wc, u); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if hasFinalizer(u) {
+		t.Errorf("Expecting the finalizer to be removed with the %s annotation", workshopForceTeardown)
+	}
+}
+
+func TestReconcileWorkshopInvalidSpec(t *testing.T) {
+	workshopFile := writeWorkshopFile(t, "http://gitea-127.0.0.1.sslip.io:30950", func(o *WorkshopOptions) {
+		//the Drone servers are deployed in the participant namespaces
+		o.GiteaUsers.DroneServers = &DroneServers{}
+	})
+	wc := newFakeWorkshopController(newWorkshop(t, workshopFile))
+	workshops := wc.client.Resource(workshopGVR).Namespace("drone")
+
+	u, _ := workshops.Get(context.TODO(), "tutorial", metav1.GetOptions{})
+	if err := wc.reconcile(context.TODO(), u); err != nil {
+		t.Fatalf("Expecting the invalid spec not to be retried but got %v", err)
+	}
+	u, _ = workshops.Get(context.TODO(), "tutorial", metav1.GetOptions{})
+	status, err := workshopStatus(u)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c := meta.FindStatusCondition(status.Conditions, workshopReady)
+	if c == nil || c.Status != metav1.ConditionFalse || c.Reason != "InvalidSpec" {
+		t.Errorf("Expecting the Workshop not to be ready with the invalid spec but got %v", c)
+	}
+
+	//nothing was provisioned with the invalid spec
+	now := metav1.Now()
+	u.SetDeletionTimestamp(&now)
+	if err := wc.reconcile(context.TODO(), u); err != nil {
+		t.Fatalf("%v", err)
+	}
+	u, _ = workshops.Get(context.TODO(), "tutorial", metav1.GetOptions{})
+	if hasFinalizer(u) {
+		t.Errorf("Expecting the finalizer of the Workshop with the invalid spec to be removed")
+	}
+}
+
+func TestWorkshopSpecSecretRef(t *testing.T) {
+	u := newWorkshop(t, writeWorkshopFile(t, "http://gitea-127.0.0.1.sslip.io:30950"))
+	clientset, err := newKubernetesClient("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	workshopOpts, err := workshopSpec(clientset, u)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if workshopOpts.GiteaAdminUser != "demo" || workshopOpts.GiteaAdminPassword != "demo@123" {
+		t.Errorf("Expecting the admin password from the secret but got %s %s", workshopOpts.GiteaAdminUser, workshopOpts.GiteaAdminPassword)
+	}
+
+	if err := unstructured.SetNestedField(u.Object, "other", "spec", "giteaAdminUserPasswordSecretRef", "key"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := workshopSpec(clientset, u); err == nil {
+		t.Errorf("Expecting the secret key other to be missing")
+	}
+
+	if err := unstructured.SetNestedField(u.Object, "demo@123", "spec", "giteaAdminUserPassword"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := workshopSpec(clientset, u); err == nil || !strings.Contains(err.Error(), "giteaAdminUserPasswordSecretRef") {
+		t.Errorf("Expecting the password in the spec to be refused but got %v", err)
+	}
+}
@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"strings"
 
+	"code.gitea.io/sdk/gitea"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -10,14 +12,22 @@ import (
 
 //OAuthAppOptions to hold the options to create oauth application
 type OAuthAppOptions struct {
+	id                  int64
 	oAuthAppName        string
-	appRedirectURL      string
+	appRedirectURLs     []string
+	confidentialClient  bool
 	giteaAdminPassword  string
 	giteaAdminUser      string
 	giteaURL            string
+	sudo                string
 	addKubernetesSecret bool
 	namespace           string
 	kubeconfig          string
+	secretName          string
+	secretProfile       string
+	secretKeyMappings   map[string]string
+	secretKeys          SecretKeys
+	secretLabels        map[string]string
 }
 
 // OAuthAppOptions implements Interface
@@ -25,13 +35,23 @@ var _ Command = (*OAuthAppOptions)(nil)
 
 var oAuthAppCommandExample = fmt.Sprintf(`
   # Create oAuthApp with defaults
-  %[1]s oauthapp --app-name my-app 
+  %[1]s oauthapp create --app-name my-app
   # Create oAuthApp with app and host
-  %[1]s oauthapp -a my-app -h http://example.com
+  %[1]s oauthapp create -a my-app -r http://example.com
+  # Create oAuthApp with the redirect URLs of both the NodePort and the ingress
+  %[1]s oauthapp create -a my-app -r http://drone-127.0.0.1.sslip.io:30980/login -r https://drone.example.com/login
+  # Create oAuthApp for a public client e.g. a CLI using PKCE
+  %[1]s oauthapp create -a my-app -r http://127.0.0.1:8000/callback --confidential=false
   # Create oAuthApp with app,host,gitea url, admin and password
-  %[1]s oauthapp -a my-app -h http://example.com -g https://try.gitea.com -u myAdmin -p myAdmin123
+  %[1]s oauthapp create -a my-app -r http://example.com -g https://try.gitea.com -u myAdmin -p myAdmin123
+  # Create oAuthApp owned by user-01
+  %[1]s oauthapp create -a my-app --sudo user-01
   # Create oAuthApp and store the client id and secret in kubernetes secret
-  %[1]s oauthapp --app-name my-app  -s -n my-namesapce
+  %[1]s oauthapp create --app-name my-app  -s -n my-namesapce
+  # Create oAuthApp and store the client id and secret in the kubernetes secret woodpecker-gitea for Woodpecker CI
+  %[1]s oauthapp create -a woodpecker -s -n woodpecker --secret-profile woodpecker --secret-name woodpecker-gitea
+  # Create oAuthApp and store the client id and secret in kubernetes secret with custom keys
+  %[1]s oauthapp create -a grafana -s -n grafana --secret-key clientID=GF_CLIENT_ID,clientSecret=GF_CLIENT_SECRET
 `, ExamplePrefix())
 
 //NewCreateOAuthAppCommand instantiates the new instance of the StartCommand
@@ -39,7 +59,7 @@ func NewCreateOAuthAppCommand() *cobra.Command {
 	oAuthOpts := &OAuthAppOptions{}
 
 	oAuthCmd := &cobra.Command{
-		Use:     "oauthapp",
+		Use:     "create",
 		Short:   "Create an Gitea OAuthApp",
 		Example: oAuthAppCommandExample,
 		RunE:    oAuthOpts.Execute,
@@ -57,23 +77,61 @@ func (opts *OAuthAppOptions) AddFlags(cmd *cobra.Command) {
 	if err := cmd.MarkFlagRequired("app-name"); err != nil {
 		log.Fatalf("Error marking flag 'app-name' as required %v", err)
 	}
-	cmd.Flags().StringVarP(&opts.appRedirectURL, "app-redirect-url", "r", "http://drone-127.0.0.1.sslip.io:30980", "The Gitea oAuth Application Redirect URL")
+	cmd.Flags().StringSliceVarP(&opts.appRedirectURLs, "app-redirect-url", "r", []string{"http://drone-127.0.0.1.sslip.io:30980"}, "The Gitea oAuth Application Redirect URLs, repeat the flag or separate them by comma to set more than one")
+	opts.addConfidentialFlag(cmd)
+	opts.addGiteaFlags(cmd)
+	opts.addSecretFlags(cmd)
+}
+
+//addConfidentialFlag adds the flag to choose if the oAuth application is a confidential client
+func (opts *OAuthAppOptions) addConfidentialFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&opts.confidentialClient, "confidential", true, "Make the oAuth application a confidential client that authenticates with its client secret, public clients have to use PKCE")
+}
+
+//addGiteaFlags adds the flags to connect to Gitea and to act as another user
+func (opts *OAuthAppOptions) addGiteaFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&opts.giteaAdminUser, "gitea-admin-user", "u", "demo", "The Gitea admin username")
 	cmd.Flags().StringVarP(&opts.giteaAdminPassword, "gitea-admin-password", "p", "demo@123", "The Gitea admin user password")
 	cmd.Flags().StringVarP(&opts.giteaURL, "gitea-url", "g", "http://gitea-127.0.0.1.sslip.io:30950", "The Gitea URL")
+	cmd.Flags().StringVar(&opts.sudo, "sudo", "", "Manage the oAuth applications of this user instead of the admin user e.g. user-01")
+}
+
+//addSecretFlags adds the flags to keep the Kubernetes secret of the oAuth application in sync
+func (opts *OAuthAppOptions) addSecretFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&opts.addKubernetesSecret, "add-k8s-secret", "s", false, "Create a Kubernetes secret with oAuth application name, to hold the client id and client secret of the oAuth application")
 	cmd.Flags().StringVarP(&opts.namespace, "k8s-namespace", "n", "", "The namespace where to create the kubernetes secret for the oAuth application")
 	cmd.Flags().StringVarP(&opts.kubeconfig, "kubeconfig", "k", "", "The kubeconfig file to use")
+	cmd.Flags().StringVar(&opts.secretName, "secret-name", "", "The name of the kubernetes secret, defaults to <app-name>-secret")
+	cmd.Flags().StringVar(&opts.secretProfile, "secret-profile", "drone", fmt.Sprintf("The keys of the kubernetes secret for the CI server or tool, one of %s", strings.Join(secretProfileNames(), ", ")))
+	cmd.Flags().StringToStringVar(&opts.secretKeyMappings, "secret-key", nil, "Custom keys of the kubernetes secret overriding the profile e.g. clientID=MY_CLIENT_ID,clientSecret=MY_CLIENT_SECRET,randomSecret=MY_SHARED_SECRET")
+}
+
+//resolveSecretLayout resolves the keys and labels of the kubernetes secret from the secret profile and keys flags
+func (opts *OAuthAppOptions) resolveSecretLayout() error {
+	var keys *SecretKeys
+	if len(opts.secretKeyMappings) > 0 {
+		keys = &SecretKeys{}
+		for k, v := range opts.secretKeyMappings {
+			switch k {
+			case "clientID":
+				keys.ClientID = v
+			case "clientSecret":
+				keys.ClientSecret = v
+			case "randomSecret":
+				keys.RandomSecret = v
+			default:
+				return fmt.Errorf("unknown secret key %q, must be one of clientID, clientSecret or randomSecret", k)
+			}
+		}
+	}
+	var err error
+	opts.secretKeys, opts.secretLabels, err = secretLayout(opts.secretProfile, keys)
+	return err
 }
 
 // Execute implements Command
 func (opts *OAuthAppOptions) Execute(cmd *cobra.Command, args []string) error {
-	wopts := &WorkshopOptions{
-		GiteaURL:           opts.giteaURL,
-		GiteaAdminUser:     opts.giteaAdminUser,
-		GiteaAdminPassword: opts.giteaAdminPassword,
-	}
-	c, err := wopts.newGiteaClient()
+	c, err := opts.newGiteaClient()
 	if err != nil {
 		return err
 	}
@@ -99,5 +157,22 @@ func (opts *OAuthAppOptions) Validate(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("require namespace to create the %s secret", opts.oAuthAppName)
 		}
 	}
-	return nil
+	return opts.resolveSecretLayout()
+}
+
+//newGiteaClient creates the Gitea client of the admin user, acting as the sudo user when it is set
+func (opts *OAuthAppOptions) newGiteaClient() (*gitea.Client, error) {
+	wopts := &WorkshopOptions{
+		GiteaURL:           opts.giteaURL,
+		GiteaAdminUser:     opts.giteaAdminUser,
+		GiteaAdminPassword: opts.giteaAdminPassword,
+	}
+	c, err := wopts.newGiteaClient()
+	if err != nil {
+		return nil, err
+	}
+	if opts.sudo != "" {
+		c.SetSudo(opts.sudo)
+	}
+	return c, nil
 }
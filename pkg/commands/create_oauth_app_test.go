@@ -10,7 +10,7 @@ import (
 
 func TestCreateOAuthAppWithDefaults(t *testing.T) {
 	rootCmd := NewRootCommand()
-	rootCmd.SetArgs([]string{"oauthapp", "-a", "defaults", "-v", "debug"})
+	rootCmd.SetArgs([]string{"oauthapp", "create", "-a", "defaults", "-v", "debug"})
 	if err := rootCmd.Execute(); err != nil {
 		t.Fatalf("%v", err)
 	}
@@ -18,7 +18,7 @@ func TestCreateOAuthAppWithDefaults(t *testing.T) {
 
 func TestCreateOAuthAppUpdate(t *testing.T) {
 	rootCmd := NewRootCommand()
-	rootCmd.SetArgs([]string{"oauthapp", "-a", "defaults", "-v", "debug"})
+	rootCmd.SetArgs([]string{"oauthapp", "create", "-a", "defaults", "-v", "debug"})
 	if err := rootCmd.Execute(); err != nil {
 		t.Fatalf("%v", err)
 	}
@@ -33,7 +33,7 @@ func TestCreateOAuthAppWithK8sSecret(t *testing.T) {
 	if kubeconfig == "" {
 		t.Fatal("Unable to get and set kubeconfig")
 	}
-	rootCmd.SetArgs([]string{"oauthapp", "-a", "k8s-secret", "-s", "-n", "default", "-k", kubeconfig, "-v", "debug"})
+	rootCmd.SetArgs([]string{"oauthapp", "create", "-a", "k8s-secret", "-s", "-n", "default", "-k", kubeconfig, "-v", "debug"})
 	if err := rootCmd.Execute(); err != nil {
 		t.Fatalf("%v", err)
 	}
@@ -41,7 +41,7 @@ func TestCreateOAuthAppWithK8sSecret(t *testing.T) {
 
 func TestCreateOAuthAppWithNoName(t *testing.T) {
 	rootCmd := NewRootCommand()
-	rootCmd.SetArgs([]string{"oauthapp", "-v", "debug"})
+	rootCmd.SetArgs([]string{"oauthapp", "create", "-v", "debug"})
 	eErr := fmt.Errorf("required flag(s) \"app-name\" not set")
 	if err := rootCmd.Execute(); err != nil {
 		if err.Error() != eErr.Error() {
@@ -52,7 +52,7 @@ func TestCreateOAuthAppWithNoName(t *testing.T) {
 
 func TestCreateOAuthAppWithK8sSecretNoNamespace(t *testing.T) {
 	rootCmd := NewRootCommand()
-	rootCmd.SetArgs([]string{"oauthapp", "-a", "k8s-secret-no-ns", "-s", "-v", "debug"})
+	rootCmd.SetArgs([]string{"oauthapp", "create", "-a", "k8s-secret-no-ns", "-s", "-v", "debug"})
 	eErr := fmt.Errorf("require namespace to create the k8s-secret-no-ns secret")
 	if err := rootCmd.Execute(); err != nil {
 		if err.Error() != eErr.Error() {
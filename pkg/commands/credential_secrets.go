@@ -0,0 +1,263 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+//ArgoCDRepoSecrets is the configuration of the Argo CD repository secrets of the participant repos
+type ArgoCDRepoSecrets struct {
+	//Namespace is the Go template of the namespace to create the secrets in, defaults to argocd
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+//TektonGitSecrets is the configuration of the basic-auth git credential secrets that Tekton, Kaniko and
+//other in-cluster tools clone the participant repos with, the secret is bound to the participant service account
+type TektonGitSecrets struct {
+	//Namespace is the Go template of the namespace to create the secret and service account in,
+	//defaults to the participant namespace or to default when the workshop has no participant namespaces
+	Namespace string `yaml:"namespace,omitempty"`
+	//ServiceAccount is the Go template of the participant service account name,
+	//defaults to the service account of the participant namespace or to {{ .UserName }}
+	ServiceAccount string `yaml:"serviceAccount,omitempty"`
+}
+
+//applyCredentialSecrets creates or updates the Kubernetes secrets holding the credentials of the participant
+func (opts *WorkshopOptions) applyCredentialSecrets(p *Participant, kubeconfig string) error {
+	giteaUsers := opts.GiteaUsers
+	if giteaUsers.ArgoCDRepoSecrets == nil && giteaUsers.TektonGitSecrets == nil {
+		return nil
+	}
+
+	clientset, err := newKubernetesClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	if a := giteaUsers.ArgoCDRepoSecrets; a != nil {
+		namespace, err := a.namespace(p)
+		if err != nil {
+			return err
+		}
+		for _, repoURL := range p.RepoCloneURLs {
+			secret, err := argoCDRepoSecret(p, repoURL)
+			if err != nil {
+				return err
+			}
+			secret.Namespace = namespace
+			if err := applySecret(clientset, secret); err != nil {
+				return err
+			}
+		}
+	}
+
+	if tk := giteaUsers.TektonGitSecrets; tk != nil {
+		namespace, serviceAccount, err := tk.serviceAccount(p)
+		if err != nil {
+			return err
+		}
+		secret := opts.gitBasicAuthSecret(p)
+		secret.Namespace = namespace
+		if err := applySecret(clientset, secret); err != nil {
+			return err
+		}
+		if err := applyServiceAccount(clientset, namespace, serviceAccount, secret.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//namespace returns the namespace of the Argo CD repository secrets of the participant
+func (a ArgoCDRepoSecrets) namespace(p *Participant) (string, error) {
+	if a.Namespace == "" {
+		return "argocd", nil
+	}
+	return renderTemplate(a.Namespace, p)
+}
+
+//serviceAccount returns the namespace and name of the service account the git secret of the participant is bound to
+func (tk TektonGitSecrets) serviceAccount(p *Participant) (string, string, error) {
+	namespace, serviceAccount := "default", p.UserName
+	if p.Namespace != "" {
+		namespace, serviceAccount = p.Namespace, p.ServiceAccount
+	}
+	var err error
+	if tk.Namespace != "" {
+		if namespace, err = renderTemplate(tk.Namespace, p); err != nil {
+			return "", "", err
+		}
+	}
+	if tk.ServiceAccount != "" {
+		if serviceAccount, err = renderTemplate(tk.ServiceAccount, p); err != nil {
+			return "", "", err
+		}
+	}
+	return namespace, serviceAccount, nil
+}
+
+//gitBasicAuthSecret returns the basic-auth secret with the participant credentials,
+//the annotation tells Tekton to use it for the git repos of the Gitea server
+func (opts *WorkshopOptions) gitBasicAuthSecret(p *Participant) *apiv1.Secret {
+	return &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%s-git-basic-auth", p.UserName),
+			Annotations: map[string]string{
+				"tekton.dev/git-0": opts.GiteaURL,
+			},
+		},
+		Type: apiv1.SecretTypeBasicAuth,
+		StringData: map[string]string{
+			apiv1.BasicAuthUsernameKey: p.UserName,
+			apiv1.BasicAuthPasswordKey: p.Password,
+		},
+	}
+}
+
+//applyServiceAccount creates the service account or updates the existing one, binding the secret to it when set
+func applyServiceAccount(clientset kubernetes.Interface, namespace, name, secretName string) error {
+	serviceAccounts := clientset.CoreV1().ServiceAccounts(namespace)
+	sa, err := serviceAccounts.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		sa = &apiv1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		}
+		if secretName != "" {
+			sa.Secrets = []apiv1.ObjectReference{{Name: secretName}}
+		}
+		if _, err := serviceAccounts.Create(context.TODO(), sa, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes service account %s/%s", namespace, name)
+		return nil
+	}
+
+	if secretName == "" {
+		return nil
+	}
+	for _, s := range sa.Secrets {
+		if s.Name == secretName {
+			return nil
+		}
+	}
+	sa.Secrets = append(sa.Secrets, apiv1.ObjectReference{Name: secretName})
+	if _, err := serviceAccounts.Update(context.TODO(), sa, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	log.Infof("Bound secret %s to Kubernetes service account %s/%s", secretName, namespace, name)
+	return nil
+}
+
+//argoCDRepoSecret returns the Argo CD repository secret that lets Argo CD clone the participant repo
+func argoCDRepoSecret(p *Participant, repoURL string) (*apiv1.Secret, error) {
+	repoName, err := repoNameFromURL(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: kubernetesName(fmt.Sprintf("repo-%s-%s", p.UserName, repoName)),
+			Labels: map[string]string{
+				"argocd.argoproj.io/secret-type": "repository",
+			},
+		},
+		StringData: map[string]string{
+			"type":     "git",
+			"name":     fmt.Sprintf("%s-%s", p.UserName, repoName),
+			"url":      repoURL,
+			"username": p.UserName,
+			"password": p.Password,
+		},
+	}, nil
+}
+
+//managedByLabel is the label of the objects created by the helper, its value is managedBy. The existing secrets
+//without it are neither updated nor deleted, as they are not the helper's to overwrite
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedBy      = "drone-tutorial-gitea-helper"
+)
+
+//managedLabels returns the labels along with the label of the objects created by the helper
+func managedLabels(labels map[string]string) map[string]string {
+	managed := map[string]string{managedByLabel: managedBy}
+	for k, v := range labels {
+		managed[k] = v
+	}
+	return managed
+}
+
+//isManagedSecret reports whether the secret was created by the helper
+func isManagedSecret(secret *apiv1.Secret) bool {
+	return secret.Labels[managedByLabel] == managedBy
+}
+
+//applySecret creates the secret or updates the existing one with the same name, an existing secret
+//not created by the helper is not updated
+func applySecret(clientset kubernetes.Interface, secret *apiv1.Secret) error {
+	secret.Labels = managedLabels(secret.Labels)
+	secrets := clientset.CoreV1().Secrets(secret.Namespace)
+	existing, err := secrets.Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if _, err := secrets.Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes secret %s/%s", secret.Namespace, secret.Name)
+		return nil
+	}
+	if !isManagedSecret(existing) {
+		return fmt.Errorf("the secret %s/%s exists and is not labelled %s=%s, it is not updated", secret.Namespace, secret.Name, managedByLabel, managedBy)
+	}
+
+	existing.Labels = secret.Labels
+	existing.Annotations = secret.Annotations
+	existing.Data = nil
+	existing.StringData = secret.StringData
+	if _, err := secrets.Update(context.TODO(), existing, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	log.Infof("Updated Kubernetes secret %s/%s", secret.Namespace, secret.Name)
+	return nil
+}
+
+//invalidNameChars are the characters not allowed in a DNS label, the strictest Kubernetes object name
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+//kubernetesName returns the name as a valid Kubernetes object name of at most 253 characters, lowercased with
+//the invalid characters replaced by -, a name that had to be changed gets a hash suffix of the original name
+//to keep it unique
+func kubernetesName(name string) string {
+	const maxLength = 253
+	valid := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if valid == name && len(valid) <= maxLength {
+		return name
+	}
+	//room for the - and the 8 characters of the hash
+	if len(valid) > maxLength-9 {
+		valid = strings.Trim(valid[:maxLength-9], "-")
+	}
+	//a name must start with an alphanumeric character
+	if valid == "" {
+		valid = "x"
+	}
+	return fmt.Sprintf("%s-%x", valid, sha256.Sum256([]byte(name)))[:len(valid)+9]
+}
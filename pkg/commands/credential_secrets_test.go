@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestArgoCDRepoSecret(t *testing.T) {
+	p := &Participant{UserName: "user-01", Password: "s3cr3t"}
+	secret, err := argoCDRepoSecret(p, "http://gitea-127.0.0.1.sslip.io:30950/user-01/jar-stack.git")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if secret.Name != "repo-user-01-jar-stack" || secret.Labels["argocd.argoproj.io/secret-type"] != "repository" {
+		t.Errorf("Expecting the Argo CD repository secret repo-user-01-jar-stack but got %s %v", secret.Name, secret.Labels)
+	}
+	expected := map[string]string{
+		"type":     "git",
+		"name":     "user-01-jar-stack",
+		"url":      "http://gitea-127.0.0.1.sslip.io:30950/user-01/jar-stack.git",
+		"username": "user-01",
+		"password": "s3cr3t",
+	}
+	if !reflect.DeepEqual(expected, secret.StringData) {
+		t.Errorf("Expecting the repository credentials %v but got %v", expected, secret.StringData)
+	}
+}
+
+func TestArgoCDRepoSecretName(t *testing.T) {
+	p := &Participant{UserName: "user-01"}
+	names := make(map[string]bool)
+	for _, repoURL := range []string{
+		"http://gitea.example.com/user-01/Jar_Stack.git",
+		"http://gitea.example.com/user-01/jar.stack.git",
+		"http://gitea.example.com/user-01/jar-stack.git",
+	} {
+		secret, err := argoCDRepoSecret(p, repoURL)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if errs := validation.IsDNS1123Subdomain(secret.Name); len(errs) > 0 {
+			t.Errorf("Expecting a valid secret name for %s but got %s, %v", repoURL, secret.Name, errs)
+		}
+		names[secret.Name] = true
+	}
+	if len(names) != 3 {
+		t.Errorf("Expecting a secret name per repo but got %v", names)
+	}
+}
+
+func TestKubernetesName(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	names := make(map[string]bool)
+	for _, name := range []string{"jar-stack", "___", "_-_", long, long + "b", strings.Repeat("A", 300)} {
+		actual := kubernetesName(name)
+		if errs := validation.IsDNS1123Subdomain(actual); len(errs) > 0 {
+			t.Errorf("Expecting a valid name for %s but got %s, %v", name, actual, errs)
+		}
+		names[actual] = true
+	}
+	if len(names) != 6 {
+		t.Errorf("Expecting a name per original name but got %v", names)
+	}
+	if actual := kubernetesName("jar-stack"); actual != "jar-stack" {
+		t.Errorf("Expecting the valid name jar-stack to be kept but got %s", actual)
+	}
+}
+
+func TestApplyCredentialSecretsExistingParticipant(t *testing.T) {
+	clientset := useFakeKubernetes(t)
+	s := newFakeGitea(t, existingParticipantHandlers())
+	//the secrets are enabled after user-01 was provisioned
+	workshopFile := writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.GiteaUsers.To = 1
+		o.GiteaUsers.ArgoCDRepoSecrets = &ArgoCDRepoSecrets{}
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"setup-workshop", "-f", workshopFile, "--skip-pipeline-validation"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	secret, err := clientset.CoreV1().Secrets("argocd").Get(context.TODO(), "repo-user-01-jar-stack", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expecting the Argo CD repository secret of the existing participant, %v", err)
+	}
+	if secret.StringData["url"] != "http://gitea.example.com/user-01/jar-stack.git" {
+		t.Errorf("Expecting the secret of the participant repo but got %v", secret.StringData)
+	}
+}
+
+func TestGitBasicAuthSecret(t *testing.T) {
+	opts := &WorkshopOptions{GiteaURL: "http://gitea-127.0.0.1.sslip.io:30950"}
+	secret := opts.gitBasicAuthSecret(&Participant{UserName: "user-01", Password: "s3cr3t"})
+	if secret.Name != "user-01-git-basic-auth" || secret.Type != apiv1.SecretTypeBasicAuth {
+		t.Errorf("Expecting the basic-auth secret user-01-git-basic-auth but got %s %s", secret.Name, secret.Type)
+	}
+	if secret.Annotations["tekton.dev/git-0"] != "http://gitea-127.0.0.1.sslip.io:30950" {
+		t.Errorf("Expecting the secret to be annotated for the Gitea server but got %v", secret.Annotations)
+	}
+	expected := map[string]string{"username": "user-01", "password": "s3cr3t"}
+	if !reflect.DeepEqual(expected, secret.StringData) {
+		t.Errorf("Expecting the credentials %v but got %v", expected, secret.StringData)
+	}
+}
+
+func TestApplyTektonGitSecretsExistingParticipant(t *testing.T) {
+	clientset := useFakeKubernetes(t, &apiv1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "user-01", Namespace: "pipelines"},
+	})
+	s := newFakeGitea(t, existingParticipantHandlers())
+	//the secrets are enabled after user-01 was provisioned
+	workshopFile := writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.GiteaUsers.To = 1
+		o.GiteaUsers.TektonGitSecrets = &TektonGitSecrets{Namespace: "pipelines"}
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"setup-workshop", "-f", workshopFile, "--skip-pipeline-validation"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	secret, err := clientset.CoreV1().Secrets("pipelines").Get(context.TODO(), "user-01-git-basic-auth", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expecting the git secret of the existing participant, %v", err)
+	}
+	if secret.StringData[apiv1.BasicAuthPasswordKey] != "user-01@123" {
+		t.Errorf("Expecting the participant credentials but got %v", secret.StringData)
+	}
+	sa, err := clientset.CoreV1().ServiceAccounts("pipelines").Get(context.TODO(), "user-01", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if expected := []apiv1.ObjectReference{{Name: "user-01-git-basic-auth"}}; !reflect.DeepEqual(expected, sa.Secrets) {
+		t.Errorf("Expecting the existing service account to be bound to the secret but got %v", sa.Secrets)
+	}
+}
+
+func TestSecretsNotManaged(t *testing.T) {
+	secret := &apiv1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "repo-user-01-jar-stack", Namespace: "argocd"}}
+	clientset := useFakeKubernetes(t, secret.DeepCopy())
+
+	if err := applySecret(clientset, secret.DeepCopy()); err == nil {
+		t.Error("Expecting the existing secret not created by the helper not to be updated")
+	}
+	if err := deleteSecret(clientset, "argocd", secret.Name); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := clientset.CoreV1().Secrets("argocd").Get(context.TODO(), secret.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("Expecting the secret not created by the helper to be kept but got %v", err)
+	}
+
+	managed := &apiv1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "git-basic-auth", Namespace: "argocd"}}
+	if err := applySecret(clientset, managed); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := applySecret(clientset, managed); err != nil {
+		t.Errorf("Expecting the secret created by the helper to be updated but got %v", err)
+	}
+	if err := deleteSecret(clientset, "argocd", managed.Name); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := clientset.CoreV1().Secrets("argocd").Get(context.TODO(), managed.Name, metav1.GetOptions{}); err == nil {
+		t.Error("Expecting the secret created by the helper to be deleted")
+	}
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+//loadCredentials reads the participant credentials stored in the credentials file keyed by the username,
+//an empty map is returned if the file does not exist yet
+func loadCredentials(credentialsFile string) (map[string]*Participant, error) {
+	creds := make(map[string]*Participant)
+	b, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return creds, nil
+		}
+		return nil, err
+	}
+	var participants []*Participant
+	if err := yamlv2.Unmarshal(b, &participants); err != nil {
+		return nil, err
+	}
+	for _, p := range participants {
+		creds[p.UserName] = p
+	}
+	return creds, nil
+}
+
+//saveCredentials writes the participant credentials to the credentials file ordered by the participant index,
+//the file holds passwords hence it is readable only by its owner
+func saveCredentials(credentialsFile string, creds map[string]*Participant) error {
+	participants := make([]*Participant, 0, len(creds))
+	for _, p := range creds {
+		participants = append(participants, p)
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].Index < participants[j].Index
+	})
+	b, err := yamlv2.Marshal(participants)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(credentialsFile, b, 0600)
+}
+
+//storedCredentials overrides the default credentials of the participant with the ones in the credentials file
+func (opts *WorkshopOptions) storedCredentials(p *Participant) error {
+	if opts.CredentialsFile == "" {
+		return nil
+	}
+	creds, err := loadCredentials(opts.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	if stored, ok := creds[p.UserName]; ok {
+		//the signup participants are not in the roster
+		if stored.Email != "" {
+			p.Email = stored.Email
+		}
+		if stored.FullName != "" {
+			p.FullName = stored.FullName
+		}
+		p.Password = stored.Password
+		p.AccessTokens = stored.AccessTokens
+		p.Kubeconfig = stored.Kubeconfig
+	}
+	return nil
+}
+
+//storeCredentials writes the credentials of the participant to the credentials file when the workshop has one
+func (opts *WorkshopOptions) storeCredentials(p *Participant) error {
+	if opts.CredentialsFile == "" {
+		return nil
+	}
+	creds, err := loadCredentials(opts.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	creds[p.UserName] = p
+	return saveCredentials(opts.CredentialsFile, creds)
+}
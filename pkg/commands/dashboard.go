@@ -0,0 +1,351 @@
+package commands
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//DashboardOptions the options to serve the instructor dashboard
+type DashboardOptions struct {
+	configFile    string
+	address       string
+	refresh       time.Duration
+	webhookSecret string
+	exerciseLabel string
+}
+
+//ParticipantStatus is the provisioning state and the repo progress of a workshop participant
+type ParticipantStatus struct {
+	User        string         `json:"user"`
+	Provisioned bool           `json:"provisioned"`
+	OAuthApp    bool           `json:"oAuthApp"`
+	Repos       []RepoProgress `json:"repos"`
+	Error       string         `json:"error,omitempty"`
+}
+
+//dashboard keeps the last known status of the participants
+type dashboard struct {
+	workshopOpts  *WorkshopOptions
+	client        *gitea.Client
+	exerciseLabel string
+	webhookSecret string
+	refreshCh     chan int
+
+	mu           sync.RWMutex
+	participants map[string]*ParticipantStatus
+	updated      time.Time
+}
+
+// DashboardOptions implements Interface
+var _ Command = (*DashboardOptions)(nil)
+
+var dashboardCommandExample = fmt.Sprintf(`
+  # Serve the dashboard on http://localhost:8080
+  %[1]s serve dashboard --workshop-file workshop.yaml
+  # Poll Gitea every 5 minutes and refresh on Gitea webhooks signed with the secret
+  %[1]s serve dashboard -f workshop.yaml --refresh 5m --webhook-secret s3cr3t
+`, ExamplePrefix())
+
+//NewDashboardCommand instantiates the new instance of the DashboardCommand
+func NewDashboardCommand() *cobra.Command {
+	dashboardOpts := &DashboardOptions{}
+
+	dashboardCmd := &cobra.Command{
+		Use:     "dashboard",
+		Short:   "Serve a dashboard with the provisioning state, pipeline status and repo activity of the participants",
+		Example: dashboardCommandExample,
+		RunE:    dashboardOpts.Execute,
+		PreRunE: dashboardOpts.Validate,
+	}
+
+	dashboardOpts.AddFlags(dashboardCmd)
+
+	return dashboardCmd
+}
+
+// AddFlags implements Command
+func (opts *DashboardOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.address, "address", "a", ":8080", "The address to listen on")
+	cmd.Flags().DurationVarP(&opts.refresh, "refresh", "r", time.Minute, "How often to poll Gitea for the participants status")
+	cmd.Flags().StringVar(&opts.webhookSecret, "webhook-secret", "", "The secret of the Gitea webhooks sent to /hooks, when set unsigned webhooks are rejected")
+	cmd.Flags().StringVarP(&opts.exerciseLabel, "exercise-label", "l", "", "Count only the closed issues with this label")
+}
+
+// Validate implements Command
+func (opts *DashboardOptions) Validate(cmd *cobra.Command, args []string) error {
+	if opts.refresh <= 0 {
+		return fmt.Errorf("refresh interval must be greater than zero")
+	}
+	return nil
+}
+
+// Execute implements Command
+func (opts *DashboardOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	d := &dashboard{
+		workshopOpts:  workshopOpts,
+		client:        c,
+		exerciseLabel: opts.exerciseLabel,
+		webhookSecret: opts.webhookSecret,
+		refreshCh:     make(chan int, 100),
+		participants:  make(map[string]*ParticipantStatus),
+	}
+
+	go d.poll(opts.refresh)
+
+	return listenAndServe(opts.address, d.handler())
+}
+
+//handler returns the HTTP routes of the dashboard
+func (d *dashboard) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.serveHTML)
+	mux.HandleFunc("/api/participants", d.serveJSON)
+	mux.HandleFunc("/hooks", d.serveWebhook)
+	return mux
+}
+
+//poll refreshes all the participants on every tick and a single participant on webhook events
+func (d *dashboard) poll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	d.refreshAll()
+	for {
+		select {
+		case <-ticker.C:
+			d.refreshAll()
+		case i := <-d.refreshCh:
+			d.refresh(i)
+		}
+	}
+}
+
+//refreshAll refreshes the status of all the participants
+func (d *dashboard) refreshAll() {
+	giteaUsers := d.workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		d.refresh(i)
+	}
+}
+
+//refresh queries Gitea for the current status of the i-th participant
+func (d *dashboard) refresh(i int) {
+	user := participantUserName(i)
+	status, err := d.workshopOpts.participantStatus(d.client, i, d.exerciseLabel)
+	if err != nil {
+		log.Errorf("Error refreshing the status of %s, %v", user, err)
+		status = &ParticipantStatus{User: user, Error: err.Error()}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.participants[user] = status
+	d.updated = time.Now()
+}
+
+//snapshot returns the status of the participants ordered by their username
+func (d *dashboard) snapshot() ([]ParticipantStatus, time.Time) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var participants []ParticipantStatus
+	giteaUsers := d.workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		if s, ok := d.participants[participantUserName(i)]; ok {
+			participants = append(participants, *s)
+		}
+	}
+	return participants, d.updated
+}
+
+//participantStatus gathers the provisioning state and the repo progress of the i-th participant
+func (opts *WorkshopOptions) participantStatus(c *gitea.Client, i int, exerciseLabel string) (*ParticipantStatus, error) {
+	user := participantUserName(i)
+	status := &ParticipantStatus{User: user}
+
+	if _, resp, err := c.GetUserInfo(user); err != nil {
+		if isNotFound(resp) {
+			return status, nil
+		}
+		return nil, err
+	}
+	status.Provisioned = true
+
+	if name := opts.GiteaUsers.participant(i).OAuthAppName; name != "" {
+		//the shared oAuth applications are owned by the admin user
+		if !opts.GiteaUsers.SharedOAuthApps {
+			c.SetSudo(user)
+		}
+		o, err := findOAuthApp(c, name)
+		//Set it back to admin
+		c.SetSudo("")
+		if err != nil {
+			return nil, err
+		}
+		status.OAuthApp = o != nil
+	}
+
+	for _, repoURL := range opts.GiteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return nil, err
+		}
+		p, err := repoProgress(c, user, repoName, exerciseLabel)
+		if err != nil {
+			return nil, err
+		}
+		status.Repos = append(status.Repos, *p)
+	}
+
+	return status, nil
+}
+
+func (d *dashboard) serveJSON(w http.ResponseWriter, r *http.Request) {
+	participants, _ := d.snapshot()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(participants); err != nil {
+		log.Errorf("Error writing participants %v", err)
+	}
+}
+
+func (d *dashboard) serveHTML(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	participants, updated := d.snapshot()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, map[string]interface{}{
+		"GiteaURL":     d.workshopOpts.GiteaURL,
+		"Participants": participants,
+		"Updated":      updated,
+	}); err != nil {
+		log.Errorf("Error rendering dashboard %v", err)
+	}
+}
+
+//serveWebhook handles the Gitea webhooks by refreshing the participant owning the repository
+func (d *dashboard) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if d.webhookSecret != "" && !validWebhookSignature(d.webhookSecret, body, r.Header.Get("X-Gitea-Signature")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Repository struct {
+			Owner struct {
+				UserName string `json:"login"`
+			} `json:"owner"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := payload.Repository.Owner.UserName
+	log.Debugf("Received %s webhook for %s", r.Header.Get("X-Gitea-Event"), user)
+
+	giteaUsers := d.workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		if participantUserName(i) != user {
+			continue
+		}
+		select {
+		case d.refreshCh <- i:
+		default:
+			log.Warnf("Too many pending refreshes, dropping the webhook for %s", user)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+//validWebhookSignature checks the hex encoded HMAC SHA256 signature of the Gitea webhook body
+func validWebhookSignature(secret string, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta http-equiv="refresh" content="30">
+  <title>Workshop Dashboard</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em; }
+    table { border-collapse: collapse; width: 100%; }
+    th, td { border: 1px solid #ddd; padding: 6px 10px; text-align: left; }
+    th { background: #f4f4f4; }
+    .success { background: #d4f7d4; }
+    .failure, .error { background: #f9d0d0; }
+    .pending, .warning { background: #fff3c4; }
+  </style>
+</head>
+<body>
+  <h1>Workshop Dashboard</h1>
+  <p>Gitea <a href="{{ .GiteaURL }}">{{ .GiteaURL }}</a>, last updated {{ .Updated.Format "15:04:05" }}</p>
+  <table>
+    <tr>
+      <th>User</th><th>Provisioned</th><th>OAuth App</th><th>Repo</th><th>Commit</th><th>Message</th><th>Date</th><th>Pipeline</th><th>Open PRs</th><th>Closed Issues</th>
+    </tr>
+    {{- range .Participants }}
+    {{- $p := . }}
+    {{- if .Error }}
+    <tr class="error"><td>{{ .User }}</td><td colspan="9">{{ .Error }}</td></tr>
+    {{- else if not .Repos }}
+    <tr><td>{{ .User }}</td><td>{{ .Provisioned }}</td><td>{{ .OAuthApp }}</td><td colspan="7"></td></tr>
+    {{- end }}
+    {{- range .Repos }}
+    <tr class="{{ .Pipeline }}">
+      <td>{{ $p.User }}</td><td>{{ $p.Provisioned }}</td><td>{{ $p.OAuthApp }}</td>
+      <td>{{ .Repo }}{{ if .Missing }} (missing){{ end }}</td>
+      <td>{{ printf "%.7s" .Commit }}</td><td>{{ .CommitMessage }}</td><td>{{ .CommitDate }}</td>
+      <td>{{ if .Pipeline }}{{ .Pipeline }}{{ else }}none{{ end }}</td>
+      <td>{{ .OpenPullRequests }}</td><td>{{ .ClosedIssues }}</td>
+    </tr>
+    {{- end }}
+    {{- end }}
+  </table>
+</body>
+</html>
+`))
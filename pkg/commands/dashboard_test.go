@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/users/user-01": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"login":"user-01"}`)
+		},
+		"/api/v1/users/user-02": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"/api/v1/user/applications/oauth2": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Sudo") != "user-01" {
+				t.Errorf("Expecting oAuth apps to be listed as user-01 but got %q", r.Header.Get("Sudo"))
+			}
+			fmt.Fprint(w, `[{"id":1,"name":"demo-oauth-user-01"}]`)
+		},
+		"/api/v1/repos/user-01/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","default_branch":"main","empty":true}`)
+		},
+		"/api/v1/repos/user-01/jar-stack/pulls": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[]`)
+		},
+		"/api/v1/repos/user-01/jar-stack/issues": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[]`)
+		},
+	})
+
+	workshopOpts, err := loadWorkshopOptions(writeWorkshopFile(t, s.URL))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	workshopOpts.GiteaUsers.OAuthAppName = "demo-oauth"
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	d := &dashboard{
+		workshopOpts:  workshopOpts,
+		client:        c,
+		webhookSecret: "s3cr3t",
+		refreshCh:     make(chan int, 1),
+		participants:  make(map[string]*ParticipantStatus),
+	}
+	d.refreshAll()
+
+	rec := httptest.NewRecorder()
+	d.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/participants", nil))
+	var actual []ParticipantStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &actual); err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := []ParticipantStatus{
+		{
+			User:        "user-01",
+			Provisioned: true,
+			OAuthApp:    true,
+			Repos:       []RepoProgress{{User: "user-01", Repo: "jar-stack"}},
+		},
+		{User: "user-02"},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expecting participants %v but got %v", expected, actual)
+	}
+
+	rec = httptest.NewRecorder()
+	d.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "user-02") {
+		t.Errorf("Expecting dashboard page with the participants but got %d %s", rec.Code, rec.Body.String())
+	}
+
+	payload := `{"repository":{"owner":{"login":"user-02"}}}`
+	req := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(payload))
+	req.Header.Set("X-Gitea-Signature", "bad")
+	rec = httptest.NewRecorder()
+	d.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expecting webhook with bad signature to be rejected but got %d", rec.Code)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte(payload))
+	req = httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(payload))
+	req.Header.Set("X-Gitea-Signature", hex.EncodeToString(mac.Sum(nil)))
+	rec = httptest.NewRecorder()
+	d.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expecting webhook to be accepted but got %d", rec.Code)
+	}
+	if i := <-d.refreshCh; i != 2 {
+		t.Errorf("Expecting participant 2 to be refreshed but got %d", i)
+	}
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//droneTimeout is how long a request to the Drone API may take, synchronizing the repos is synchronous
+const droneTimeout = 2 * time.Minute
+
+//droneHTTPClient is the HTTP client of the Drone API requests
+var droneHTTPClient = &http.Client{Timeout: droneTimeout}
+
+//droneClient is a client of the Drone REST API authenticated with the API token of a Drone user
+type droneClient struct {
+	server     string
+	token      string
+	httpClient *http.Client
+}
+
+//newDroneClient returns the client of the Drone server authenticated with the API token
+func newDroneClient(server, token string) *droneClient {
+	return &droneClient{server: server, token: token, httpClient: droneHTTPClient}
+}
+
+//droneUser is the Drone user of the API token
+type droneUser struct {
+	Login     string `json:"login"`
+	Admin     bool   `json:"admin"`
+	LastLogin int64  `json:"last_login"`
+}
+
+//droneRepo is a repo of the Drone server
+type droneRepo struct {
+	Slug      string `json:"slug"`
+	Active    bool   `json:"active"`
+	Trusted   bool   `json:"trusted"`
+	Protected bool   `json:"protected"`
+}
+
+//droneRepoPatch are the repo settings to update
+type droneRepoPatch struct {
+	Trusted   *bool `json:"trusted,omitempty"`
+	Protected *bool `json:"protected,omitempty"`
+}
+
+//droneRepoSecret is a repo secret of the Drone server, the data is never returned by the server
+type droneRepoSecret struct {
+	Name        string `json:"name"`
+	Data        string `json:"data,omitempty"`
+	PullRequest bool   `json:"pull_request"`
+}
+
+//droneError is the error response of the Drone server
+type droneError struct {
+	status  int
+	message string
+}
+
+func (e *droneError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.status, http.StatusText(e.status), e.message)
+}
+
+//isDroneNotFound returns true when the Drone resource does not exist
+func isDroneNotFound(err error) bool {
+	e, ok := err.(*droneError)
+	return ok && e.status == http.StatusNotFound
+}
+
+//self returns the Drone user of the token
+func (d *droneClient) self() (*droneUser, error) {
+	u := new(droneUser)
+	return u, d.do(http.MethodGet, "/api/user", nil, u)
+}
+
+//syncRepos synchronizes the repos of the Drone user with the Gitea repos they have access to
+func (d *droneClient) syncRepos() error {
+	return d.do(http.MethodPost, "/api/user/repos?async=false", nil, nil)
+}
+
+//repo returns the Drone repo of the owner
+func (d *droneClient) repo(owner, name string) (*droneRepo, error) {
+	r := new(droneRepo)
+	return r, d.do(http.MethodGet, fmt.Sprintf("/api/repos/%s/%s", owner, name), nil, r)
+}
+
+//activateRepo activates the Drone repo of the owner, adding the webhook to the Gitea repo
+func (d *droneClient) activateRepo(owner, name string) error {
+	return d.do(http.MethodPost, fmt.Sprintf("/api/repos/%s/%s", owner, name), nil, nil)
+}
+
+//updateRepo updates the settings of the Drone repo of the owner
+func (d *droneClient) updateRepo(owner, name string, patch droneRepoPatch) error {
+	return d.do(http.MethodPatch, fmt.Sprintf("/api/repos/%s/%s", owner, name), patch, nil)
+}
+
+//applyRepoSecret creates the repo secret or updates the existing one with the same name
+func (d *droneClient) applyRepoSecret(owner, name string, secret droneRepoSecret) error {
+	path := fmt.Sprintf("/api/repos/%s/%s/secrets", owner, name)
+	err := d.do(http.MethodGet, fmt.Sprintf("%s/%s", path, secret.Name), nil, nil)
+	if err == nil {
+		return d.do(http.MethodPatch, fmt.Sprintf("%s/%s", path, secret.Name), secret, nil)
+	}
+	if !isDroneNotFound(err) {
+		return err
+	}
+	return d.do(http.MethodPost, path, secret, nil)
+}
+
+//do sends the request with the body encoded as JSON and decodes the response in to out when not nil
+func (d *droneClient) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(d.server, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return &droneError{status: resp.StatusCode, message: strings.TrimSpace(string(msg))}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,275 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+//dronePipelineFile is the Drone pipeline file of the template repos
+const dronePipelineFile = ".drone.yml"
+
+//githubRawURL is the server of the raw files of the GitHub repos, a variable for the tests
+var githubRawURL = "https://raw.githubusercontent.com"
+
+//remoteHTTPClient is the HTTP client reading the pipelines of the remote templates
+var remoteHTTPClient = &http.Client{Timeout: time.Minute}
+
+//droneKinds are the kinds of the Drone resources
+var droneKinds = map[string]bool{"pipeline": true, "secret": true, "signature": true, "template": true}
+
+//dronePipelineTypes are the pipeline types of the Drone runners, true when the steps run in containers
+var dronePipelineTypes = map[string]bool{
+	"docker":       true,
+	"kubernetes":   true,
+	"exec":         false,
+	"ssh":          false,
+	"digitalocean": false,
+	"macstadium":   false,
+	"vm":           false,
+}
+
+//droneResource is a document of the Drone pipeline file
+type droneResource struct {
+	Kind             string      `yaml:"kind"`
+	Type             string      `yaml:"type"`
+	Name             string      `yaml:"name"`
+	Steps            []droneStep `yaml:"steps"`
+	Services         []droneStep `yaml:"services"`
+	ImagePullSecrets []string    `yaml:"image_pull_secrets"`
+}
+
+//droneStep is a step or a service of a Drone pipeline
+type droneStep struct {
+	Name        string                 `yaml:"name"`
+	Image       string                 `yaml:"image"`
+	Environment map[string]interface{} `yaml:"environment"`
+	Settings    map[string]interface{} `yaml:"settings"`
+}
+
+//validateDronePipelines validates the Drone pipeline of each template repo, read from the local source
+//of the template, from the copy of the Gitea admin user or from the GitHub template, the templates without
+//pipeline are skipped
+func (opts *WorkshopOptions) validateDronePipelines(c *gitea.Client) error {
+	var problems []string
+	for _, repoURL := range opts.GiteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return err
+		}
+		b, err := opts.dronePipeline(c, repoURL, repoName)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			log.Warnf("No %s found for the template %s, skipping its validation", dronePipelineFile, repoURL)
+			continue
+		}
+		for _, problem := range opts.GiteaUsers.validateDronePipeline(b) {
+			problems = append(problems, fmt.Sprintf("%s: %s", repoName, problem))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid Drone pipelines:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+//dronePipeline returns the Drone pipeline file of the template repo, nil when the template has none
+func (opts *WorkshopOptions) dronePipeline(c *gitea.Client, repoURL, repoName string) ([]byte, error) {
+	if dir, ok := localSource(repoURL); ok {
+		b, err := ioutil.ReadFile(filepath.Join(dir, dronePipelineFile))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return b, err
+	}
+
+	repo, resp, err := c.GetRepo(opts.GiteaAdminUser, repoName)
+	if err != nil {
+		if isNotFound(resp) {
+			log.Debugf("The template %s is not migrated for %s, reading its pipeline from the remote", repoURL, opts.GiteaAdminUser)
+			return remoteDronePipeline(repoURL)
+		}
+		return nil, err
+	}
+	b, resp, err := c.GetFile(opts.GiteaAdminUser, repoName, repo.DefaultBranch, dronePipelineFile)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+//remoteDronePipeline returns the Drone pipeline file of the remote template repo, nil when the template has none,
+//only the GitHub templates could be read without cloning them
+func remoteDronePipeline(repoURL string) ([]byte, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	ownerRepo := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if u.Host != "github.com" || strings.Count(ownerRepo, "/") != 1 {
+		return nil, fmt.Errorf("can't read the %s of the template %s, migrate it for the Gitea admin user or skip the validation with --skip-pipeline-validation", dronePipelineFile, repoURL)
+	}
+
+	resp, err := remoteHTTPClient.Get(fmt.Sprintf("%s/%s/HEAD/%s", githubRawURL, ownerRepo, dronePipelineFile))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error reading the %s of the template %s, %s", dronePipelineFile, repoURL, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+//localSource returns the directory of the template repo when it is a local path
+func localSource(repoURL string) (string, bool) {
+	dir := strings.TrimPrefix(repoURL, "file://")
+	if dir == repoURL && strings.Contains(repoURL, "://") {
+		return "", false
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return "", false
+	}
+	return dir, true
+}
+
+//validateDronePipeline returns the problems of the Drone pipeline file, when the workshop has a drone setup
+//the secrets must be defined in the file or be one of the secrets created by setup-drone
+func (u GiteaUser) validateDronePipeline(b []byte) []string {
+	var problems []string
+	resources, err := parseDroneResources(b)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid YAML, %v", err)}
+	}
+
+	secrets := make(map[string]bool)
+	if u.Drone != nil {
+		for _, s := range u.Drone.Secrets {
+			secrets[s.Name] = true
+		}
+	}
+	for _, r := range resources {
+		if r.Kind == "secret" {
+			secrets[r.Name] = true
+		}
+	}
+
+	for i, r := range resources {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("document %d", i+1)
+		}
+		if !droneKinds[r.Kind] {
+			problems = append(problems, fmt.Sprintf("%s: unknown kind %q", name, r.Kind))
+			continue
+		}
+		if r.Kind != "pipeline" {
+			continue
+		}
+
+		pipelineType := r.Type
+		if pipelineType == "" {
+			pipelineType = "docker"
+		}
+		containers, ok := dronePipelineTypes[pipelineType]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown pipeline type %q", name, r.Type))
+		}
+		if len(r.Steps) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: the pipeline has no steps", name))
+		}
+
+		var refs []string
+		for _, s := range append(r.Steps, r.Services...) {
+			if containers && s.Image == "" {
+				problems = append(problems, fmt.Sprintf("%s: step %q has no image", name, s.Name))
+			}
+			refs = append(refs, fromSecrets(s.Environment)...)
+			refs = append(refs, fromSecrets(s.Settings)...)
+		}
+		refs = append(refs, r.ImagePullSecrets...)
+
+		//the secrets might be created by hand without a drone setup
+		if u.Drone == nil {
+			continue
+		}
+		sort.Strings(refs)
+		for j, ref := range refs {
+			if (j == 0 || refs[j-1] != ref) && !secrets[ref] {
+				problems = append(problems, fmt.Sprintf("%s: secret %q is not provided", name, ref))
+			}
+		}
+	}
+
+	return problems
+}
+
+//parseDroneResources parses the documents of the Drone pipeline file
+func parseDroneResources(b []byte) ([]droneResource, error) {
+	var resources []droneResource
+	d := yamlv2.NewDecoder(bytes.NewReader(b))
+	for {
+		var r droneResource
+		if err := d.Decode(&r); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		//the empty documents e.g. of a trailing --- are not resources
+		if r.Kind == "" && r.Name == "" && len(r.Steps) == 0 {
+			continue
+		}
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+//fromSecrets returns the names of the secrets the values refer to with from_secret
+func fromSecrets(v interface{}) []string {
+	var names []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if k == "from_secret" {
+				names = append(names, fmt.Sprint(e))
+				continue
+			}
+			names = append(names, fromSecrets(e)...)
+		}
+	case map[interface{}]interface{}:
+		for k, e := range v {
+			if k == "from_secret" {
+				names = append(names, fmt.Sprint(e))
+				continue
+			}
+			names = append(names, fromSecrets(e)...)
+		}
+	case []interface{}:
+		for _, e := range v {
+			names = append(names, fromSecrets(e)...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validDronePipeline = `kind: pipeline
+type: kubernetes
+name: default
+steps:
+  - name: build
+    image: maven:3-openjdk-11
+    environment:
+      GITEA_PASSWORD:
+        from_secret: gitea_password
+  - name: publish
+    image: plugins/docker
+    settings:
+      password:
+        from_secret: registry_password
+---
+kind: secret
+name: registry_password
+get:
+  path: registry
+  name: password
+`
+
+func TestValidateDronePipeline(t *testing.T) {
+	giteaUsers := GiteaUser{Drone: &DroneSetup{Secrets: []DroneSecret{{Name: "gitea_password"}}}}
+	if problems := giteaUsers.validateDronePipeline([]byte(validDronePipeline)); len(problems) != 0 {
+		t.Errorf("Expecting the pipeline to be valid but got %v", problems)
+	}
+
+	broken := `kind: pipeline
+type: podman
+name: default
+steps:
+  - name: build
+    environment:
+      TOKEN:
+        from_secret: token
+---
+kind: pipe
+name: typo
+`
+	expected := []string{
+		`default: unknown pipeline type "podman"`,
+		`default: secret "token" is not provided`,
+		`typo: unknown kind "pipe"`,
+	}
+	if problems := giteaUsers.validateDronePipeline([]byte(broken)); !reflect.DeepEqual(expected, problems) {
+		t.Errorf("Expecting the problems %v but got %v", expected, problems)
+	}
+
+	expected = []string{`default: step "build" has no image`}
+	if problems := giteaUsers.validateDronePipeline([]byte(strings.Replace(broken, "podman", "docker", 1))); !reflect.DeepEqual(expected, problems[:1]) {
+		t.Errorf("Expecting the problems to start with %v but got %v", expected, problems)
+	}
+
+	if problems := giteaUsers.validateDronePipeline([]byte("kind: pipeline\n  steps: [")); len(problems) != 1 || !strings.HasPrefix(problems[0], "invalid YAML") {
+		t.Errorf("Expecting the invalid YAML to be reported but got %v", problems)
+	}
+
+	//without a drone setup the secrets could be created by hand
+	if problems := (GiteaUser{}).validateDronePipeline([]byte(validDronePipeline)); len(problems) != 0 {
+		t.Errorf("Expecting the secrets not to be checked without a drone setup but got %v", problems)
+	}
+}
+
+func TestValidatePipelinesLocalSource(t *testing.T) {
+	templateDir := filepath.Join(t.TempDir(), "jar-stack")
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatalf("%v", err)
+	}
+	pipeline := "kind: pipeline\nname: default\nsteps:\n  - name: build\n"
+	if err := ioutil.WriteFile(filepath.Join(templateDir, dronePipelineFile), []byte(pipeline), 0644); err != nil {
+		t.Fatalf("%v", err)
+	}
+	s := newFakeGitea(t, nil)
+	workshopFile := writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.GiteaUsers.Repos = []string{templateDir}
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"validate-pipelines", "-f", workshopFile})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `jar-stack: default: step "build" has no image`) {
+		t.Errorf("Expecting the step without image of the local template to be reported but got %v", err)
+	}
+}
+
+func TestValidatePipelinesAdminCopy(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/repos/demo/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","default_branch":"main"}`)
+		},
+		"/api/v1/repos/demo/jar-stack/raw/main/.drone.yml": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, validDronePipeline)
+		},
+	})
+	workshopFile := writeWorkshopFile(t, s.URL)
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"validate-pipelines", "-f", workshopFile})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("Expecting the pipeline of the admin copy to be valid but got %v", err)
+	}
+}
+
+func TestValidatePipelinesRemoteTemplate(t *testing.T) {
+	github := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/kameshsampath/jar-stack/HEAD/.drone.yml": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "kind: pipeline\nname: default\nsteps:\n  - name: build\n")
+		},
+	})
+	rawURL := githubRawURL
+	githubRawURL = github.URL
+	t.Cleanup(func() {
+		githubRawURL = rawURL
+	})
+	//the template is not migrated for the admin user
+	s := newFakeGitea(t, nil)
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"validate-pipelines", "-f", writeWorkshopFile(t, s.URL)})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `jar-stack: default: step "build" has no image`) {
+		t.Errorf("Expecting the pipeline of the GitHub template to be validated but got %v", err)
+	}
+
+	//a remote that can't be read is not silently skipped
+	workshopFile := writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.GiteaUsers.Repos = []string{"https://gitlab.example.com/kameshsampath/jar-stack.git"}
+	})
+	rootCmd = NewRootCommand()
+	rootCmd.SetArgs([]string{"validate-pipelines", "-f", workshopFile})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--skip-pipeline-validation") {
+		t.Errorf("Expecting the validation of an unreadable template to fail but got %v", err)
+	}
+}
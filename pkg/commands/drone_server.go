@@ -0,0 +1,460 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	defaultDroneServerImage = "drone/drone:2"
+	defaultDroneRunnerImage = "drone/drone-runner-kube:latest"
+	//droneAdminSecretName is the secret holding the API token of the participant as the Drone server admin
+	droneAdminSecretName = "drone-admin"
+	droneAdminTokenKey   = "token"
+)
+
+//DroneServers is the configuration of the Drone server and Kubernetes runner deployed in each participant namespace,
+//the server signs in with the first oAuth application of the participant and is reachable at the host of its redirect URI
+type DroneServers struct {
+	//ServerImage is the image of the Drone server, defaults to drone/drone:2
+	ServerImage string `yaml:"serverImage,omitempty"`
+	//RunnerImage is the image of the Drone Kubernetes runner, defaults to drone/drone-runner-kube:latest
+	RunnerImage string `yaml:"runnerImage,omitempty"`
+	//ServiceType is the type of the Drone server service, defaults to ClusterIP. A ClusterIP service is exposed
+	//by an Ingress for the host of the redirect URI, a NodePort or LoadBalancer one must be routed to by that host
+	ServiceType string `yaml:"serviceType,omitempty"`
+	//IngressClassName is the class of the Ingress of the Drone server, defaults to the default class of the cluster
+	IngressClassName string `yaml:"ingressClassName,omitempty"`
+}
+
+//droneServer is the Drone server of a participant
+type droneServer struct {
+	namespace      string
+	serviceAccount string
+	admin          string
+	giteaURL       string
+	proto          string
+	host           string
+	secretName     string
+	secretKeys     SecretKeys
+}
+
+//validateDroneServers checks the participant Drone servers can be deployed with the workshop configuration
+func (u GiteaUser) validateDroneServers() error {
+	if u.DroneServers == nil {
+		return nil
+	}
+	if u.ParticipantNamespaces == nil {
+		return fmt.Errorf("the participant Drone servers require the participantNamespaces")
+	}
+	if u.SharedOAuthApps {
+		return fmt.Errorf("the participant Drone servers can't use the shared oAuth applications")
+	}
+	if !u.AddKubernetesSecret {
+		return fmt.Errorf("the participant Drone servers require the oAuth application secrets, set addKubernetesSecret")
+	}
+	switch apiv1.ServiceType(u.DroneServers.ServiceType) {
+	case "", apiv1.ServiceTypeClusterIP:
+	case apiv1.ServiceTypeNodePort, apiv1.ServiceTypeLoadBalancer:
+		log.Warnf("The host of the redirect URI must route to the %s service of each Drone server", u.DroneServers.ServiceType)
+	default:
+		return fmt.Errorf("invalid serviceType %q of the Drone servers, one of ClusterIP, NodePort or LoadBalancer", u.DroneServers.ServiceType)
+	}
+	//the pipeline pods of the runner have no resources, the limit range gives them those the quota requires
+	if quota := u.ParticipantNamespaces.ResourceQuota; u.ParticipantNamespaces.LimitRange == nil {
+		var computeResources []string
+		for r := range quota {
+			if strings.HasSuffix(r, "cpu") || strings.HasSuffix(r, "memory") {
+				computeResources = append(computeResources, r)
+			}
+		}
+		if len(computeResources) > 0 {
+			sort.Strings(computeResources)
+			return fmt.Errorf("the participant Drone servers require a participantNamespaces limitRange with the resourceQuota on %s, the pipeline pods have no resources", strings.Join(computeResources, ", "))
+		}
+	}
+	apps := u.oAuthAppList()
+	if len(apps) == 0 {
+		return fmt.Errorf("the participant Drone servers require an oAuth application")
+	}
+	o, err := apps[0].options(u.participant(u.From))
+	if err != nil {
+		return err
+	}
+	if _, err := newDroneServer(nil, o, ""); err != nil {
+		return err
+	}
+	return nil
+}
+
+//newDroneServer returns the Drone server of the participant signing in with the oAuth application
+func newDroneServer(p *Participant, o *OAuthAppOptions, giteaURL string) (*droneServer, error) {
+	if len(o.appRedirectURLs) == 0 {
+		return nil, fmt.Errorf("the oAuth application %s of the Drone server has no redirect URI", o.oAuthAppName)
+	}
+	u, err := url.Parse(o.appRedirectURLs[0])
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid redirect URI %q of the Drone server, it has no host", o.appRedirectURLs[0])
+	}
+	if o.secretKeys.RandomSecret == "" {
+		return nil, fmt.Errorf("the secret of the oAuth application %s has no random secret key for the DRONE_RPC_SECRET", o.oAuthAppName)
+	}
+	ds := &droneServer{
+		giteaURL:   strings.TrimSuffix(giteaURL, "/"),
+		proto:      u.Scheme,
+		host:       u.Host,
+		secretName: o.kubernetesSecretName(),
+		secretKeys: o.secretKeys,
+	}
+	if p != nil {
+		ds.namespace, ds.serviceAccount, ds.admin = p.Namespace, p.ServiceAccount, p.UserName
+	}
+	return ds, nil
+}
+
+//url returns the URL the participant signs in to the Drone server with
+func (ds *droneServer) url() string {
+	return fmt.Sprintf("%s://%s", ds.proto, ds.host)
+}
+
+//applyDroneServer deploys the Drone server and runner of the participant in their namespace
+func (opts *WorkshopOptions) applyDroneServer(p *Participant, o *OAuthAppOptions, kubeconfig string) error {
+	d := opts.GiteaUsers.DroneServers
+	if d == nil {
+		return nil
+	}
+
+	ds, err := newDroneServer(p, o, opts.GiteaURL)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := newKubernetesClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	if _, err := droneAdminToken(clientset, ds.namespace); err != nil {
+		return err
+	}
+	if err := applyDeployment(clientset, ds.serverDeployment(d)); err != nil {
+		return err
+	}
+	if err := applyService(clientset, ds.service(d)); err != nil {
+		return err
+	}
+	if ingress := ds.ingress(d); ingress != nil {
+		if err := applyIngress(clientset, ingress); err != nil {
+			return err
+		}
+	}
+	if err := applyDeployment(clientset, ds.runnerDeployment(d)); err != nil {
+		return err
+	}
+	log.Infof("Deployed the Drone server %s of %s", ds.url(), p.UserName)
+
+	return nil
+}
+
+//secretEnv returns the environment variable set from the key of the oAuth application secret
+func (ds *droneServer) secretEnv(name, key string) apiv1.EnvVar {
+	return apiv1.EnvVar{
+		Name: name,
+		ValueFrom: &apiv1.EnvVarSource{
+			SecretKeyRef: &apiv1.SecretKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{Name: ds.secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+//serverDeployment returns the deployment of the Drone server, its data is ephemeral as the workshops are
+func (ds *droneServer) serverDeployment(d *DroneServers) *appsv1.Deployment {
+	image := d.ServerImage
+	if image == "" {
+		image = defaultDroneServerImage
+	}
+	return droneDeployment(ds.namespace, "drone", ds.serviceAccount, apiv1.Container{
+		Name:  "drone",
+		Image: image,
+		Env: []apiv1.EnvVar{
+			{Name: "DRONE_GITEA_SERVER", Value: ds.giteaURL},
+			{Name: "DRONE_SERVER_HOST", Value: ds.host},
+			{Name: "DRONE_SERVER_PROTO", Value: ds.proto},
+			{
+				Name: "DRONE_ADMIN_TOKEN",
+				ValueFrom: &apiv1.EnvVarSource{
+					SecretKeyRef: &apiv1.SecretKeySelector{
+						LocalObjectReference: apiv1.LocalObjectReference{Name: droneAdminSecretName},
+						Key:                  droneAdminTokenKey,
+					},
+				},
+			},
+			//Kubernetes expands the token defined above
+			{Name: "DRONE_USER_CREATE", Value: fmt.Sprintf("username:%s,admin:true,token:$(DRONE_ADMIN_TOKEN)", ds.admin)},
+			ds.secretEnv("DRONE_GITEA_CLIENT_ID", ds.secretKeys.ClientID),
+			ds.secretEnv("DRONE_GITEA_CLIENT_SECRET", ds.secretKeys.ClientSecret),
+			ds.secretEnv("DRONE_RPC_SECRET", ds.secretKeys.RandomSecret),
+		},
+		Ports:     []apiv1.ContainerPort{{Name: "http", ContainerPort: 80}},
+		Resources: droneResources("100m", "128Mi", "500m", "512Mi"),
+		VolumeMounts: []apiv1.VolumeMount{{
+			Name:      "data",
+			MountPath: "/data",
+		}},
+	}, apiv1.Volume{
+		Name:         "data",
+		VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}},
+	})
+}
+
+//runnerDeployment returns the deployment of the Drone Kubernetes runner,
+//it runs the pipelines in the participant namespace with the participant service account
+func (ds *droneServer) runnerDeployment(d *DroneServers) *appsv1.Deployment {
+	image := d.RunnerImage
+	if image == "" {
+		image = defaultDroneRunnerImage
+	}
+	return droneDeployment(ds.namespace, "drone-runner", ds.serviceAccount, apiv1.Container{
+		Name:  "drone-runner",
+		Image: image,
+		Env: []apiv1.EnvVar{
+			{Name: "DRONE_RPC_HOST", Value: "drone"},
+			{Name: "DRONE_RPC_PROTO", Value: "http"},
+			{Name: "DRONE_NAMESPACE_DEFAULT", Value: ds.namespace},
+			{Name: "DRONE_SERVICE_ACCOUNT_DEFAULT", Value: ds.serviceAccount},
+			ds.secretEnv("DRONE_RPC_SECRET", ds.secretKeys.RandomSecret),
+		},
+		Resources: droneResources("50m", "64Mi", "250m", "256Mi"),
+	})
+}
+
+//service returns the service of the Drone server
+func (ds *droneServer) service(d *DroneServers) *apiv1.Service {
+	serviceType := apiv1.ServiceTypeClusterIP
+	if d.ServiceType != "" {
+		serviceType = apiv1.ServiceType(d.ServiceType)
+	}
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "drone",
+			Namespace: ds.namespace,
+			Labels:    droneLabels("drone"),
+		},
+		Spec: apiv1.ServiceSpec{
+			Type:     serviceType,
+			Selector: droneLabels("drone"),
+			Ports: []apiv1.ServicePort{{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromString("http"),
+			}},
+		},
+	}
+}
+
+//ingress returns the Ingress routing the host of the redirect URI to the ClusterIP service of the Drone server,
+//nil when the service is exposed by its type
+func (ds *droneServer) ingress(d *DroneServers) *networkingv1.Ingress {
+	if d.ServiceType != "" && apiv1.ServiceType(d.ServiceType) != apiv1.ServiceTypeClusterIP {
+		return nil
+	}
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "drone",
+			Namespace: ds.namespace,
+			Labels:    droneLabels("drone"),
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				//the port of the redirect URI is the one of the ingress controller
+				Host: strings.Split(ds.host, ":")[0],
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     "/",
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: "drone",
+									Port: networkingv1.ServiceBackendPort{Name: "http"},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	if d.IngressClassName != "" {
+		ingress.Spec.IngressClassName = &d.IngressClassName
+	}
+	return ingress
+}
+
+//droneResources returns the resource requests and limits of a Drone container, set for the namespace resource quotas
+func droneResources(cpu, memory, cpuLimit, memoryLimit string) apiv1.ResourceRequirements {
+	return apiv1.ResourceRequirements{
+		Requests: apiv1.ResourceList{
+			apiv1.ResourceCPU:    resource.MustParse(cpu),
+			apiv1.ResourceMemory: resource.MustParse(memory),
+		},
+		Limits: apiv1.ResourceList{
+			apiv1.ResourceCPU:    resource.MustParse(cpuLimit),
+			apiv1.ResourceMemory: resource.MustParse(memoryLimit),
+		},
+	}
+}
+
+//droneLabels returns the labels of the Drone component
+func droneLabels(component string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name": component,
+		managedByLabel:           managedBy,
+	}
+}
+
+//droneDeployment returns the single replica deployment of the Drone component
+func droneDeployment(namespace, name, serviceAccount string, container apiv1.Container, volumes ...apiv1.Volume) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    droneLabels(name),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: droneLabels(name)},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: droneLabels(name)},
+				Spec: apiv1.PodSpec{
+					ServiceAccountName: serviceAccount,
+					Containers:         []apiv1.Container{container},
+					Volumes:            volumes,
+				},
+			},
+		},
+	}
+}
+
+//droneAdminToken returns the API token of the Drone server admin of the namespace,
+//the token is generated when the secret does not exist yet
+func droneAdminToken(clientset kubernetes.Interface, namespace string) (string, error) {
+	secrets := clientset.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(context.TODO(), droneAdminSecretName, metav1.GetOptions{})
+	if err == nil {
+		return string(secret.Data[droneAdminTokenKey]), nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return "", err
+	}
+	token, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	_, err = secrets.Create(context.TODO(), &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      droneAdminSecretName,
+			Namespace: namespace,
+			Labels:    droneLabels("drone"),
+		},
+		StringData: map[string]string{droneAdminTokenKey: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+	log.Infof("Created Kubernetes secret %s/%s", namespace, droneAdminSecretName)
+	return token, nil
+}
+
+//applyDeployment creates the deployment or updates the spec of the existing one
+func applyDeployment(clientset kubernetes.Interface, deployment *appsv1.Deployment) error {
+	deployments := clientset.AppsV1().Deployments(deployment.Namespace)
+	existing, err := deployments.Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if _, err := deployments.Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes deployment %s/%s", deployment.Namespace, deployment.Name)
+		return nil
+	}
+	existing.Labels = deployment.Labels
+	existing.Spec = deployment.Spec
+	_, err = deployments.Update(context.TODO(), existing, metav1.UpdateOptions{})
+	return err
+}
+
+//applyService creates the service or updates the type, selector and ports of the existing one
+func applyService(clientset kubernetes.Interface, service *apiv1.Service) error {
+	services := clientset.CoreV1().Services(service.Namespace)
+	existing, err := services.Get(context.TODO(), service.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if _, err := services.Create(context.TODO(), service, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes service %s/%s", service.Namespace, service.Name)
+		return nil
+	}
+	//the cluster IP and node ports allocated to the service are kept
+	existing.Labels = service.Labels
+	existing.Spec.Type = service.Spec.Type
+	existing.Spec.Selector = service.Spec.Selector
+	for i := range service.Spec.Ports {
+		if service.Spec.Type == apiv1.ServiceTypeClusterIP {
+			break
+		}
+		for _, port := range existing.Spec.Ports {
+			if port.Name == service.Spec.Ports[i].Name {
+				service.Spec.Ports[i].NodePort = port.NodePort
+			}
+		}
+	}
+	existing.Spec.Ports = service.Spec.Ports
+	_, err = services.Update(context.TODO(), existing, metav1.UpdateOptions{})
+	return err
+}
+
+//applyIngress creates the ingress or updates the spec of the existing one
+func applyIngress(clientset kubernetes.Interface, ingress *networkingv1.Ingress) error {
+	ingresses := clientset.NetworkingV1().Ingresses(ingress.Namespace)
+	existing, err := ingresses.Get(context.TODO(), ingress.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if _, err := ingresses.Create(context.TODO(), ingress, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes ingress %s/%s", ingress.Namespace, ingress.Name)
+		return nil
+	}
+	existing.Labels = ingress.Labels
+	existing.Spec = ingress.Spec
+	_, err = ingresses.Update(context.TODO(), existing, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//droneWorkshop returns a workshop deploying a Drone server for each participant at drone-<user>.example.com
+func droneWorkshop() *WorkshopOptions {
+	return &WorkshopOptions{
+		GiteaURL: "http://gitea-127.0.0.1.sslip.io:30950/",
+		GiteaUsers: GiteaUser{
+			From:                  1,
+			To:                    2,
+			AddKubernetesSecret:   true,
+			OAuthAppName:          "drone",
+			OAuthRedirectURIs:     []string{"https://drone-{{ .UserName }}.example.com/login"},
+			ParticipantNamespaces: &ParticipantNamespaces{},
+			DroneServers:          &DroneServers{},
+		},
+	}
+}
+
+func TestDroneServer(t *testing.T) {
+	workshopOpts := droneWorkshop()
+	p := workshopOpts.GiteaUsers.participant(1)
+	oAuthApps, err := workshopOpts.participantOAuthApps(p, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ds, err := newDroneServer(p, oAuthApps[0], workshopOpts.GiteaURL)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	server := ds.serverDeployment(workshopOpts.GiteaUsers.DroneServers)
+	if server.Namespace != "user-01" || server.Spec.Template.Spec.ServiceAccountName != "user-01" {
+		t.Errorf("Expecting the Drone server in the namespace user-01 but got %s", server.Namespace)
+	}
+	env := make(map[string]apiv1.EnvVar)
+	for _, e := range server.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e
+	}
+	for name, expected := range map[string]string{
+		"DRONE_GITEA_SERVER": "http://gitea-127.0.0.1.sslip.io:30950",
+		"DRONE_SERVER_HOST":  "drone-user-01.example.com",
+		"DRONE_SERVER_PROTO": "https",
+		"DRONE_USER_CREATE":  "username:user-01,admin:true,token:$(DRONE_ADMIN_TOKEN)",
+	} {
+		if env[name].Value != expected {
+			t.Errorf("Expecting %s to be %s but got %s", name, expected, env[name].Value)
+		}
+	}
+	if ref := env["DRONE_GITEA_CLIENT_SECRET"].ValueFrom.SecretKeyRef; ref.Name != "drone-user-01-secret" || ref.Key != "DRONE_GITEA_CLIENT_SECRET" {
+		t.Errorf("Expecting the client secret from the oAuth application secret but got %v", ref)
+	}
+
+	runner := ds.runnerDeployment(workshopOpts.GiteaUsers.DroneServers)
+	if image := runner.Spec.Template.Spec.Containers[0].Image; image != defaultDroneRunnerImage {
+		t.Errorf("Expecting the runner image %s but got %s", defaultDroneRunnerImage, image)
+	}
+
+	if service := ds.service(workshopOpts.GiteaUsers.DroneServers); service.Spec.Type != apiv1.ServiceTypeClusterIP || service.Spec.Ports[0].Port != 80 {
+		t.Errorf("Expecting a ClusterIP service on port 80 but got %v", service.Spec)
+	}
+
+	for _, d := range []*apiv1.Container{&server.Spec.Template.Spec.Containers[0], &runner.Spec.Template.Spec.Containers[0]} {
+		if _, ok := d.Resources.Requests[apiv1.ResourceCPU]; !ok {
+			t.Errorf("Expecting the %s container to request resources for the namespace quota but got %v", d.Name, d.Resources)
+		}
+	}
+
+	ingress := ds.ingress(workshopOpts.GiteaUsers.DroneServers)
+	if ingress == nil || ingress.Spec.Rules[0].Host != "drone-user-01.example.com" || ingress.Spec.IngressClassName != nil {
+		t.Fatalf("Expecting an ingress of the default class for the host of the redirect URI but got %v", ingress)
+	}
+	if backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service; backend.Name != "drone" || backend.Port.Name != "http" {
+		t.Errorf("Expecting the ingress to route to the Drone server service but got %v", backend)
+	}
+	if ingress := ds.ingress(&DroneServers{ServiceType: "NodePort"}); ingress != nil {
+		t.Errorf("Expecting no ingress for a NodePort service but got %v", ingress)
+	}
+
+	if url := workshopOpts.droneURL(p); url != "https://drone-user-01.example.com" {
+		t.Errorf("Expecting the participant to sign in to their own Drone server but got %s", url)
+	}
+}
+
+func TestValidateDroneServers(t *testing.T) {
+	if err := droneWorkshop().GiteaUsers.validateDroneServers(); err != nil {
+		t.Errorf("Expecting the Drone servers to be valid but got %v", err)
+	}
+
+	for name, modify := range map[string]func(*GiteaUser){
+		"no participant namespaces": func(u *GiteaUser) { u.ParticipantNamespaces = nil },
+		"shared oAuth apps":         func(u *GiteaUser) { u.SharedOAuthApps = true },
+		"no secrets":                func(u *GiteaUser) { u.AddKubernetesSecret = false },
+		"no rpc secret":             func(u *GiteaUser) { u.SecretProfile = "generic" },
+		"invalid service type":      func(u *GiteaUser) { u.DroneServers.ServiceType = "ExternalName" },
+		"quota without limit range": func(u *GiteaUser) { u.ParticipantNamespaces.ResourceQuota = map[string]string{"requests.cpu": "2"} },
+	} {
+		workshopOpts := droneWorkshop()
+		modify(&workshopOpts.GiteaUsers)
+		if err := workshopOpts.GiteaUsers.validateDroneServers(); err == nil {
+			t.Errorf("Expecting the Drone servers with %s to be invalid", name)
+		}
+	}
+}
+
+func TestValidateDroneServersLimitRange(t *testing.T) {
+	workshopOpts := droneWorkshop()
+	workshopOpts.GiteaUsers.ParticipantNamespaces = &ParticipantNamespaces{
+		ResourceQuota: map[string]string{"requests.cpu": "2", "requests.memory": "4Gi", "pods": "10"},
+		LimitRange:    &ContainerLimits{DefaultRequest: map[string]string{"cpu": "100m", "memory": "128Mi"}},
+	}
+	if err := workshopOpts.GiteaUsers.validateDroneServers(); err != nil {
+		t.Errorf("Expecting the Drone servers with a limit range to be valid but got %v", err)
+	}
+	workshopOpts.GiteaUsers.ParticipantNamespaces = &ParticipantNamespaces{ResourceQuota: map[string]string{"pods": "10"}}
+	if err := workshopOpts.GiteaUsers.validateDroneServers(); err != nil {
+		t.Errorf("Expecting the Drone servers with a quota on pods only to be valid but got %v", err)
+	}
+}
+
+func TestApplyDroneServerIngress(t *testing.T) {
+	clientset := useFakeKubernetes(t)
+	workshopOpts := droneWorkshop()
+	workshopOpts.GiteaUsers.DroneServers.IngressClassName = "nginx"
+	p := workshopOpts.GiteaUsers.participant(1)
+	oAuthApps, err := workshopOpts.participantOAuthApps(p, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	//applied twice the objects are updated
+	for i := 0; i < 2; i++ {
+		if err := workshopOpts.applyDroneServer(p, oAuthApps[0], ""); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	ingress, err := clientset.NetworkingV1().Ingresses("user-01").Get(context.TODO(), "drone", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expecting the ingress of the Drone server, %v", err)
+	}
+	if ingress.Spec.IngressClassName == nil || *ingress.Spec.IngressClassName != "nginx" {
+		t.Errorf("Expecting the ingress class nginx but got %v", ingress.Spec.IngressClassName)
+	}
+	server, err := clientset.AppsV1().Deployments("user-01").Get(context.TODO(), "drone", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if memory := server.Spec.Template.Spec.Containers[0].Resources.Requests[apiv1.ResourceMemory]; memory.Cmp(resource.MustParse("128Mi")) != 0 {
+		t.Errorf("Expecting the Drone server to request 128Mi but got %s", memory.String())
+	}
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+//DroneValuesOptions the options to render the Drone Helm chart values of the participants
+type DroneValuesOptions struct {
+	configFile string
+	outputDir  string
+}
+
+// DroneValuesOptions implements Interface
+var _ Command = (*DroneValuesOptions)(nil)
+
+var droneValuesCommandExample = fmt.Sprintf(`
+  # Render the Drone Helm values of each participant in to the directory 'drone-values'
+  %[1]s render drone-values --workshop-file workshop.yaml
+  # Install the Drone server of user-01 with the rendered values
+  %[1]s render drone-values -f workshop.yaml -d values
+  helm upgrade --install drone drone/drone -n user-01 -f values/user-01.yaml
+`, ExamplePrefix())
+
+//droneValues are the values of the Drone Helm chart, the oAuth application secret is
+//read as environment variables as its keys are the Drone settings
+type droneValues struct {
+	Env                        map[string]string `yaml:"env"`
+	ExtraSecretNamesForEnvFrom []string          `yaml:"extraSecretNamesForEnvFrom"`
+}
+
+//NewDroneValuesCommand instantiates the new instance of the DroneValuesCommand
+func NewDroneValuesCommand() *cobra.Command {
+	droneValuesOpts := &DroneValuesOptions{}
+
+	droneValuesCmd := &cobra.Command{
+		Use:     "drone-values",
+		Short:   "Render a Drone Helm chart values file for each participant",
+		Example: droneValuesCommandExample,
+		RunE:    droneValuesOpts.Execute,
+		PreRunE: droneValuesOpts.Validate,
+	}
+
+	droneValuesOpts.AddFlags(droneValuesCmd)
+
+	return droneValuesCmd
+}
+
+// AddFlags implements Command
+func (opts *DroneValuesOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.outputDir, "output-dir", "d", "drone-values", "The directory to write the values files to")
+}
+
+// Validate implements Command
+func (opts *DroneValuesOptions) Validate(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// Execute implements Command
+func (opts *DroneValuesOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	giteaUsers := workshopOpts.GiteaUsers
+	if !giteaUsers.AddKubernetesSecret {
+		return fmt.Errorf("the Drone values refer to the oAuth application secrets, set addKubernetesSecret in %s", opts.configFile)
+	}
+
+	if err := os.MkdirAll(opts.outputDir, 0755); err != nil {
+		return err
+	}
+
+	//the shared Drone server is the same for all the participants
+	if giteaUsers.SharedOAuthApps {
+		return opts.writeDroneValues(workshopOpts, nil)
+	}
+
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		if err := opts.writeDroneValues(workshopOpts, giteaUsers.participant(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//writeDroneValues writes the Drone values of the participant, of the shared Drone server when the participant is nil
+func (opts *DroneValuesOptions) writeDroneValues(workshopOpts *WorkshopOptions, p *Participant) error {
+	oAuthApps, err := workshopOpts.participantOAuthApps(p, "")
+	if err != nil {
+		return err
+	}
+	if len(oAuthApps) == 0 {
+		return fmt.Errorf("the Drone values require an oAuth application in %s", opts.configFile)
+	}
+	//the Drone server signs in with the first oAuth application
+	o := oAuthApps[0]
+
+	values, err := newDroneValues(p, o, workshopOpts.GiteaURL)
+	if err != nil {
+		return err
+	}
+	b, err := yamlv2.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	name := o.oAuthAppName
+	if p != nil {
+		name = p.UserName
+	}
+	file := filepath.Join(opts.outputDir, name+".yaml")
+	header := fmt.Sprintf("# helm upgrade --install drone drone/drone -n %s -f %s\n", o.namespace, filepath.Base(file))
+	if err := ioutil.WriteFile(file, append([]byte(header), b...), 0644); err != nil {
+		return err
+	}
+
+	log.Infof("Wrote the Drone values of %s to %s", name, file)
+
+	return nil
+}
+
+//newDroneValues returns the Drone values of the Drone server signing in with the oAuth application
+func newDroneValues(p *Participant, o *OAuthAppOptions, giteaURL string) (*droneValues, error) {
+	if o.secretKeys != droneSecretKeys {
+		return nil, fmt.Errorf("the Drone chart reads the secret of the oAuth application %s as environment variables, it requires the drone secret profile", o.oAuthAppName)
+	}
+	ds, err := newDroneServer(p, o, giteaURL)
+	if err != nil {
+		return nil, err
+	}
+	values := &droneValues{
+		Env: map[string]string{
+			"DRONE_GITEA_SERVER": ds.giteaURL,
+			"DRONE_SERVER_HOST":  ds.host,
+			"DRONE_SERVER_PROTO": ds.proto,
+		},
+		ExtraSecretNamesForEnvFrom: []string{ds.secretName},
+	}
+	if p != nil {
+		values.Env["DRONE_USER_CREATE"] = fmt.Sprintf("username:%s,admin:true", p.UserName)
+	}
+	return values, nil
+}
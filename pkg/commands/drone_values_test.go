@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+func TestRenderDroneValues(t *testing.T) {
+	workshopFile := writeWorkshopFile(t, "http://gitea-127.0.0.1.sslip.io:30950/", func(o *WorkshopOptions) {
+		o.GiteaUsers.AddKubernetesSecret = true
+		o.GiteaUsers.SecretNamespace = "drone"
+		o.GiteaUsers.OAuthAppName = "drone"
+		o.GiteaUsers.OAuthRedirectURIs = []string{"https://drone-{{ .UserName }}.example.com/login"}
+	})
+	outputDir := t.TempDir()
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"render", "drone-values", "-f", workshopFile, "-d", outputDir})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(outputDir, "user-02.yaml"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.HasPrefix(string(b), "# helm upgrade --install drone drone/drone -n drone -f user-02.yaml\n") {
+		t.Errorf("Expecting the values to start with the helm command but got\n%s", b)
+	}
+	var values droneValues
+	if err := yamlv2.Unmarshal(b, &values); err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := droneValues{
+		Env: map[string]string{
+			"DRONE_GITEA_SERVER": "http://gitea-127.0.0.1.sslip.io:30950",
+			"DRONE_SERVER_HOST":  "drone-user-02.example.com",
+			"DRONE_SERVER_PROTO": "https",
+			"DRONE_USER_CREATE":  "username:user-02,admin:true",
+		},
+		ExtraSecretNamesForEnvFrom: []string{"drone-user-02-secret"},
+	}
+	if !reflect.DeepEqual(expected, values) {
+		t.Errorf("Expecting the Drone values %v but got %v", expected, values)
+	}
+}
+
+func TestRenderDroneValuesShared(t *testing.T) {
+	workshopFile := writeWorkshopFile(t, "http://gitea-127.0.0.1.sslip.io:30950/", func(o *WorkshopOptions) {
+		o.GiteaUsers.AddKubernetesSecret = true
+		o.GiteaUsers.SharedOAuthApps = true
+		o.GiteaUsers.OAuthAppName = "drone"
+		o.GiteaUsers.OAuthRedirectURI = "http://drone-127.0.0.1.sslip.io:30980"
+	})
+	outputDir := t.TempDir()
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"render", "drone-values", "-f", workshopFile, "-d", outputDir})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	files, err := ioutil.ReadDir(outputDir)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(files) != 1 || files[0].Name() != "drone.yaml" {
+		t.Errorf("Expecting only the values of the shared Drone server drone.yaml but got %v", files)
+	}
+}
+
+func TestRenderDroneValuesCustomKeys(t *testing.T) {
+	workshopFile := writeWorkshopFile(t, "http://gitea-127.0.0.1.sslip.io:30950/", func(o *WorkshopOptions) {
+		o.GiteaUsers.AddKubernetesSecret = true
+		o.GiteaUsers.OAuthAppName = "drone"
+		o.GiteaUsers.OAuthRedirectURI = "http://drone-127.0.0.1.sslip.io:30980"
+		o.GiteaUsers.SecretProfile = "woodpecker"
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"render", "drone-values", "-f", workshopFile, "-d", t.TempDir()})
+	if err := rootCmd.Execute(); err == nil {
+		t.Errorf("Expecting the Drone values of a woodpecker secret to fail")
+	}
+}
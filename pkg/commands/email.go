@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//SMTP is the configuration of the SMTP server used to email the credentials to the participants
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	From     string `yaml:"from"`
+	Subject  string `yaml:"subject,omitempty"`
+	//Template is the path to the Go template of the email body, defaults to defaultEmailTemplate
+	Template string `yaml:"template,omitempty"`
+}
+
+const defaultEmailTemplate = `Hello {{ with .Participant.FullName }}{{ . }}{{ else }}{{ .Participant.UserName }}{{ end }},
+
+Your workshop account is ready.
+
+Gitea:    {{ .GiteaURL }}
+Username: {{ .Participant.UserName }}
+Password: {{ .Participant.Password }}
+{{- with .DroneURL }}
+Drone:    {{ . }}
+{{- end }}
+{{- if .Participant.RepoCloneURLs }}
+
+Your repositories:
+{{- range .Participant.RepoCloneURLs }}
+  {{ . }}
+{{- end }}
+{{- end }}
+
+Happy building!
+`
+
+//credentialsData is the data available to the credentials templates
+type credentialsData struct {
+	GiteaURL    string
+	DroneURL    string
+	Participant *Participant
+}
+
+//credentialsData returns the template data of the participant credentials
+func (opts *WorkshopOptions) credentialsData(p *Participant) credentialsData {
+	return credentialsData{
+		GiteaURL:    opts.GiteaURL,
+		DroneURL:    opts.droneURL(p),
+		Participant: p,
+	}
+}
+
+//droneURL returns the URL of the Drone server the participant signs in to,
+//their own Drone server when the workshop deploys one for each participant
+func (opts *WorkshopOptions) droneURL(p *Participant) string {
+	giteaUsers := opts.GiteaUsers
+	if giteaUsers.DroneServers != nil {
+		if apps := giteaUsers.oAuthAppList(); len(apps) > 0 {
+			if o, err := apps[0].options(p); err == nil {
+				if ds, err := newDroneServer(p, o, opts.GiteaURL); err == nil {
+					return ds.url()
+				}
+			}
+		}
+	}
+	return giteaUsers.OAuthRedirectURI
+}
+
+//sendCredentials emails the credentials to the participant when SMTP is configured
+func (opts *WorkshopOptions) sendCredentials(p *Participant) error {
+	if opts.SMTP == nil || opts.SMTP.Host == "" {
+		return nil
+	}
+
+	msg, err := opts.credentialsEmail(p)
+	if err != nil {
+		return err
+	}
+
+	s := opts.SMTP
+	port := s.Port
+	if port == 0 {
+		port = 25
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	if err := smtp.SendMail(net.JoinHostPort(s.Host, strconv.Itoa(port)), auth, s.From, []string{p.Email}, msg); err != nil {
+		return err
+	}
+	log.Infof("Sent the credentials of %s to %s", p.UserName, p.Email)
+
+	return nil
+}
+
+//credentialsEmail renders the email message with the credentials of the participant
+func (opts *WorkshopOptions) credentialsEmail(p *Participant) ([]byte, error) {
+	s := opts.SMTP
+	text := defaultEmailTemplate
+	if s.Template != "" {
+		b, err := ioutil.ReadFile(s.Template)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+	tmpl, err := template.New("email").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := s.Subject
+	if subject == "" {
+		subject = "Your workshop credentials"
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", p.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprint(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprint(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	if err := tmpl.Execute(&msg, opts.credentialsData(p)); err != nil {
+		return nil, err
+	}
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredentialsEmail(t *testing.T) {
+	workshopOpts := &WorkshopOptions{
+		GiteaURL: "http://gitea-127.0.0.1.sslip.io:30950/",
+		GiteaUsers: GiteaUser{
+			From:             1,
+			To:               2,
+			OAuthAppName:     "demo-oauth",
+			OAuthRedirectURI: "http://drone-127.0.0.1.sslip.io:30980",
+			Roster:           []Attendee{{Name: "Jane Doe", Email: "jane@example.com"}},
+		},
+		SMTP: &SMTP{Host: "localhost", Port: 1025, From: "workshop@example.com"},
+	}
+
+	p := workshopOpts.GiteaUsers.participant(1)
+	p.RepoCloneURLs = []string{"http://gitea-127.0.0.1.sslip.io:30950/user-01/jar-stack.git"}
+	msg, err := workshopOpts.credentialsEmail(p)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, expected := range []string{
+		"From: workshop@example.com\r\n",
+		"To: jane@example.com\r\n",
+		"Subject: Your workshop credentials\r\n",
+		"Hello Jane Doe,",
+		"Username: user-01\n",
+		"Password: user-01@123\n",
+		"Drone:    http://drone-127.0.0.1.sslip.io:30980\n",
+		"  http://gitea-127.0.0.1.sslip.io:30950/user-01/jar-stack.git\n",
+	} {
+		if !strings.Contains(string(msg), expected) {
+			t.Errorf("Expecting email to contain %q but got\n%s", expected, msg)
+		}
+	}
+
+	//participants without roster entry keep the generated email
+	if p := workshopOpts.GiteaUsers.participant(2); p.Email != "user-02@example.com" || p.FullName != "" {
+		t.Errorf("Expecting user-02 to have the default email but got %v", p)
+	}
+}
+
+func TestCredentialsEmailWithTemplate(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "email.tmpl")
+	if err := ioutil.WriteFile(tmpl, []byte("Login to {{ .GiteaURL }} as {{ .Participant.UserName }}"), 0644); err != nil {
+		t.Fatalf("%v", err)
+	}
+	workshopOpts := &WorkshopOptions{
+		GiteaURL: "http://gitea",
+		SMTP:     &SMTP{Host: "localhost", From: "workshop@example.com", Subject: "Drone Workshop", Template: tmpl},
+	}
+
+	msg, err := workshopOpts.credentialsEmail(workshopOpts.GiteaUsers.participant(3))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.HasSuffix(string(msg), "\r\n\r\nLogin to http://gitea as user-03") || !strings.Contains(string(msg), "Subject: Drone Workshop\r\n") {
+		t.Errorf("Expecting the email to be rendered from the template but got\n%s", msg)
+	}
+}
+
+func TestParticipantCredentialsEmail(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/users/user-01": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"login":"user-01","full_name":"Jane Doe","email":"jane@example.com"}`)
+		},
+		"/api/v1/users/user-02": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"login":"user-02","email":"john@example.com"}`)
+		},
+		"/api/v1/users/user-03": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+	})
+	workshopOpts := &WorkshopOptions{
+		GiteaURL:           s.URL,
+		GiteaAdminUser:     "demo",
+		GiteaAdminPassword: "demo@123",
+		GiteaUsers: GiteaUser{
+			From:   1,
+			To:     3,
+			Roster: []Attendee{{Name: "Jane", Email: "jane@roster.example.com"}},
+		},
+		CredentialsFile: filepath.Join(t.TempDir(), "credentials.yaml"),
+	}
+	if err := saveCredentials(workshopOpts.CredentialsFile, map[string]*Participant{
+		"user-02": {Index: 2, UserName: "user-02", Email: "john.doe@example.com", Password: "secret"},
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	//the stored email first, then the Gitea user one e.g. the signup one and last the roster one
+	for i, expected := range map[int]string{
+		1: "jane@example.com",
+		2: "john.doe@example.com",
+		3: "user-03@example.com",
+	} {
+		p, err := workshopOpts.participantCredentials(c, i)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if p.Email != expected {
+			t.Errorf("Expecting the email of user-%02d to be %s but got %s", i, expected, p.Email)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+//newFakeGitea starts a Gitea API stand in that serves the version endpoint and the given handlers
+func newFakeGitea(t *testing.T, handlers map[string]http.HandlerFunc) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/version", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"version":"1.17.0"}`)
+	})
+	for p, h := range handlers {
+		mux.HandleFunc(p, h)
+	}
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+//writeWorkshopFile writes a workshop configuration pointing to the giteaURL with users user-01 and user-02,
+//the configuration could be customized using the modifiers
+func writeWorkshopFile(t *testing.T, giteaURL string, modifiers ...func(*WorkshopOptions)) string {
+	workshopFile := filepath.Join(t.TempDir(), "workshop.yaml")
+	workshopOpts := WorkshopOptions{
+		GiteaAdminUser:     "demo",
+		GiteaAdminPassword: "demo@123",
+		GiteaURL:           giteaURL,
+		GiteaUsers: GiteaUser{
+			From:  1,
+			To:    2,
+			Repos: []string{"https://github.com/kameshsampath/jar-stack"},
+		},
+	}
+	for _, m := range modifiers {
+		m(&workshopOpts)
+	}
+	b, err := yamlv2.Marshal(workshopOpts)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := ioutil.WriteFile(workshopFile, b, 0644); err != nil {
+		t.Fatalf("%v", err)
+	}
+	return workshopFile
+}
+
+//useFakeKubernetes makes the commands use a fake clientset with the objects until the end of the test
+func useFakeKubernetes(t *testing.T, objects ...runtime.Object) *fake.Clientset {
+	clientset := fake.NewSimpleClientset(objects...)
+	newClient := newKubernetesClient
+	newKubernetesClient = func(kubeconfig string) (kubernetes.Interface, error) {
+		return clientset, nil
+	}
+	t.Cleanup(func() {
+		newKubernetesClient = newClient
+	})
+	return clientset
+}
+
+//existingParticipantHandlers are the handlers of a Gitea with user-01 and its jar-stack repo already provisioned
+func existingParticipantHandlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/v1/users/user-01": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"id":2,"login":"user-01"}`)
+		},
+		"/api/v1/repos/user-01/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","clone_url":"http://gitea.example.com/user-01/jar-stack.git"}`)
+		},
+	}
+}
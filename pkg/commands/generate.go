@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+//NewGenerateCommand instantiates the new instance of the GenerateCommand that groups
+//the commands that generate the Kubernetes manifests of the workshop
+func NewGenerateCommand() *cobra.Command {
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the Kubernetes manifests of the workshop",
+	}
+
+	generateCmd.AddCommand(NewManifestsCommand())
+
+	return generateCmd
+}
@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+	qrcode "github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+)
+
+//HandoutsOptions the options to generate the printable credential handouts
+type HandoutsOptions struct {
+	configFile string
+	outputDir  string
+	format     string
+	template   string
+}
+
+// HandoutsOptions implements Interface
+var _ Command = (*HandoutsOptions)(nil)
+
+var handoutsCommandExample = fmt.Sprintf(`
+  # Generate a HTML handout per participant in to the directory 'handouts'
+  %[1]s handouts --workshop-file workshop.yaml
+  # Generate Markdown handouts using a custom template
+  %[1]s handouts -f workshop.yaml --format markdown --template handout.md.tmpl
+`, ExamplePrefix())
+
+//NewHandoutsCommand instantiates the new instance of the HandoutsCommand
+func NewHandoutsCommand() *cobra.Command {
+	handoutsOpts := &HandoutsOptions{}
+
+	handoutsCmd := &cobra.Command{
+		Use:     "handouts",
+		Short:   "Generate printable credential handouts for the participants",
+		Example: handoutsCommandExample,
+		RunE:    handoutsOpts.Execute,
+		PreRunE: handoutsOpts.Validate,
+	}
+
+	handoutsOpts.AddFlags(handoutsCmd)
+
+	return handoutsCmd
+}
+
+// AddFlags implements Command
+func (opts *HandoutsOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.outputDir, "output-dir", "d", "handouts", "The directory to write the handouts to")
+	cmd.Flags().StringVarP(&opts.format, "format", "t", "html", "The handout format, one of html or markdown")
+	cmd.Flags().StringVar(&opts.template, "template", "", "The Go template of the handout, defaults to the built-in template of the format")
+}
+
+// Validate implements Command
+func (opts *HandoutsOptions) Validate(cmd *cobra.Command, args []string) error {
+	if opts.format != "html" && opts.format != "markdown" {
+		return fmt.Errorf("unsupported handout format %q, must be one of html or markdown", opts.format)
+	}
+	return nil
+}
+
+// Execute implements Command
+func (opts *HandoutsOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(opts.outputDir, 0755); err != nil {
+		return err
+	}
+
+	giteaUsers := workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		userName := participantUserName(i)
+		if _, resp, err := c.GetUserInfo(userName); err != nil {
+			if isNotFound(resp) {
+				log.Warnf("User %s is not provisioned, skipping", userName)
+				continue
+			}
+			return err
+		}
+
+		p, err := workshopOpts.participantCredentials(c, i)
+		if err != nil {
+			return err
+		}
+		if err := opts.writeHandout(workshopOpts, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//writeHandout renders the handout of the participant in to the output directory
+func (opts *HandoutsOptions) writeHandout(workshopOpts *WorkshopOptions, p *Participant) error {
+	ext := ".html"
+	text := defaultHTMLHandoutTemplate
+	if opts.format == "markdown" {
+		ext = ".md"
+		text = defaultMarkdownHandoutTemplate
+	}
+	if opts.template != "" {
+		b, err := ioutil.ReadFile(opts.template)
+		if err != nil {
+			return err
+		}
+		text = string(b)
+	}
+
+	f, err := os.Create(filepath.Join(opts.outputDir, p.UserName+ext))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data := workshopOpts.credentialsData(p)
+	if opts.format == "markdown" {
+		err = opts.renderMarkdownHandout(f, text, p, data)
+	} else {
+		err = renderHTMLHandout(f, text, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Wrote the handout of %s to %s", p.UserName, f.Name())
+
+	return nil
+}
+
+//renderHTMLHandout renders the handout with the QR codes inlined as PNG data URIs
+func renderHTMLHandout(w io.Writer, text string, data credentialsData) error {
+	tmpl, err := htmltemplate.New("handout").Funcs(htmltemplate.FuncMap{
+		"qrcode": func(content string) (htmltemplate.URL, error) {
+			png, err := qrcode.Encode(content, qrcode.Medium, 256)
+			if err != nil {
+				return "", err
+			}
+			return htmltemplate.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)), nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
+
+//renderMarkdownHandout renders the handout with the QR codes written as PNG files next to it
+func (opts *HandoutsOptions) renderMarkdownHandout(w io.Writer, text string, p *Participant, data credentialsData) error {
+	n := 0
+	tmpl, err := template.New("handout").Funcs(template.FuncMap{
+		"qrcode": func(content string) (string, error) {
+			n++
+			name := fmt.Sprintf("%s-qr-%d.png", p.UserName, n)
+			if err := qrcode.WriteFile(content, qrcode.Medium, 256, filepath.Join(opts.outputDir, name)); err != nil {
+				return "", err
+			}
+			return name, nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
+
+const defaultHTMLHandoutTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Workshop credentials of {{ .Participant.UserName }}</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em; }
+    dt { font-weight: bold; margin-top: 0.5em; }
+    .qr { display: inline-block; text-align: center; margin: 1em; }
+    .qr img { width: 160px; height: 160px; }
+    pre { background: #f4f4f4; padding: 1em; font-size: 0.7em; white-space: pre-wrap; word-break: break-all; }
+  </style>
+</head>
+<body>
+  <h1>{{ with .Participant.FullName }}{{ . }}{{ else }}Workshop Credentials{{ end }}</h1>
+  <dl>
+    <dt>Gitea</dt><dd>{{ .GiteaURL }}</dd>
+    <dt>Username</dt><dd>{{ .Participant.UserName }}</dd>
+    <dt>Password</dt><dd>{{ .Participant.Password }}</dd>
+    {{- with .DroneURL }}
+    <dt>Drone</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- with .Participant.SecretName }}
+    <dt>OAuth Secret</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- range .Participant.RepoCloneURLs }}
+    <dt>Repository</dt><dd>{{ . }}</dd>
+    {{- end }}
+  </dl>
+  <div class="qr"><img src="{{ qrcode .GiteaURL }}" alt="Gitea"><br>Gitea</div>
+  {{- with .DroneURL }}
+  <div class="qr"><img src="{{ qrcode . }}" alt="Drone"><br>Drone</div>
+  {{- end }}
+  {{- range .Participant.RepoCloneURLs }}
+  <div class="qr"><img src="{{ qrcode . }}" alt="{{ . }}"><br>Repository</div>
+  {{- end }}
+  {{- with .Participant.Kubeconfig }}
+  <h2>Kubeconfig</h2>
+  <pre>{{ . }}</pre>
+  {{- end }}
+</body>
+</html>
+`
+
+const defaultMarkdownHandoutTemplate = `# {{ with .Participant.FullName }}{{ . }}{{ else }}Workshop Credentials{{ end }}
+
+| | |
+| --- | --- |
+| Gitea | {{ .GiteaURL }} |
+| Username | ` + "`{{ .Participant.UserName }}`" + ` |
+| Password | ` + "`{{ .Participant.Password }}`" + ` |
+{{- with .DroneURL }}
+| Drone | {{ . }} |
+{{- end }}
+{{- with .Participant.SecretName }}
+| OAuth Secret | ` + "`{{ . }}`" + ` |
+{{- end }}
+{{- range .Participant.RepoCloneURLs }}
+| Repository | {{ . }} |
+{{- end }}
+
+![Gitea]({{ qrcode .GiteaURL }})
+{{- with .DroneURL }}
+![Drone]({{ qrcode . }})
+{{- end }}
+{{- range .Participant.RepoCloneURLs }}
+![Repository]({{ qrcode . }})
+{{- end }}
+{{- with .Participant.Kubeconfig }}
+
+## Kubeconfig
+
+` + "```yaml" + `
+{{ . }}` + "```" + `
+{{- end }}
+`
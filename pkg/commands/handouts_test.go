@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteHandouts(t *testing.T) {
+	workshopOpts := &WorkshopOptions{
+		GiteaURL: "http://gitea-127.0.0.1.sslip.io:30950/",
+		GiteaUsers: GiteaUser{
+			From:                1,
+			To:                  1,
+			OAuthAppName:        "demo-oauth",
+			OAuthRedirectURI:    "http://drone-127.0.0.1.sslip.io:30980",
+			AddKubernetesSecret: true,
+		},
+	}
+	p := workshopOpts.GiteaUsers.participant(1)
+	p.RepoCloneURLs = []string{"http://gitea-127.0.0.1.sslip.io:30950/user-01/jar-stack.git"}
+
+	outputDir := t.TempDir()
+	opts := &HandoutsOptions{outputDir: outputDir, format: "html"}
+	if err := opts.writeHandout(workshopOpts, p); err != nil {
+		t.Fatalf("%v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(outputDir, "user-01.html"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	html := string(b)
+	for _, expected := range []string{"user-01@123", "demo-oauth-user-01-secret", "jar-stack.git", `src="data:image/png;base64,`} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expecting the HTML handout to contain %s", expected)
+		}
+	}
+	if n := strings.Count(html, "data:image/png"); n != 3 {
+		t.Errorf("Expecting 3 QR codes in the HTML handout but got %d", n)
+	}
+
+	opts.format = "markdown"
+	if err := opts.writeHandout(workshopOpts, p); err != nil {
+		t.Fatalf("%v", err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(outputDir, "user-01.md"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, expected := range []string{"| Password | `user-01@123` |", "![Gitea](user-01-qr-1.png)", "![Repository](user-01-qr-3.png)"} {
+		if !strings.Contains(string(b), expected) {
+			t.Errorf("Expecting the Markdown handout to contain %s but got\n%s", expected, b)
+		}
+	}
+	for _, qr := range []string{"user-01-qr-1.png", "user-01-qr-2.png", "user-01-qr-3.png"} {
+		if _, err := os.Stat(filepath.Join(outputDir, qr)); err != nil {
+			t.Errorf("Expecting QR code %s to be written, %v", qr, err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//LockWorkshopOptions the options to lock or unlock the participant accounts
+type LockWorkshopOptions struct {
+	configFile      string
+	unlock          bool
+	ifExpired       bool
+	prohibitLogin   bool
+	restricted      bool
+	maxRepoCreation int
+}
+
+// LockWorkshopOptions implements Interface
+var _ Command = (*LockWorkshopOptions)(nil)
+
+var lockWorkshopCommandExample = fmt.Sprintf(`
+  # Prohibit the login of all the participants, restrict them and stop them from creating repos
+  %[1]s lock-workshop --workshop-file workshop.yaml
+  # Lock the participants only if the expiresAt of the workshop has passed e.g. from a CronJob
+  %[1]s lock-workshop -f workshop.yaml --if-expired
+  # Only stop the participants from creating new repos
+  %[1]s lock-workshop -f workshop.yaml --prohibit-login=false --restricted=false
+`, ExamplePrefix())
+
+var unlockWorkshopCommandExample = fmt.Sprintf(`
+  # Allow all the participants to login and create repos again
+  %[1]s unlock-workshop --workshop-file workshop.yaml
+`, ExamplePrefix())
+
+//NewLockWorkshopCommand instantiates the new instance of the LockWorkshopCommand
+func NewLockWorkshopCommand() *cobra.Command {
+	lockOpts := &LockWorkshopOptions{}
+
+	lockCmd := &cobra.Command{
+		Use:     "lock-workshop",
+		Short:   "Lock the participant accounts without deleting their work",
+		Example: lockWorkshopCommandExample,
+		RunE:    lockOpts.Execute,
+		PreRunE: lockOpts.Validate,
+	}
+
+	lockOpts.AddFlags(lockCmd)
+	lockCmd.Flags().BoolVar(&lockOpts.ifExpired, "if-expired", false, "Lock the accounts only when the expiresAt of the workshop has passed")
+	lockCmd.Flags().BoolVar(&lockOpts.prohibitLogin, "prohibit-login", true, "Prohibit the participants from signing in")
+	lockCmd.Flags().BoolVar(&lockOpts.restricted, "restricted", true, "Restrict the participants to the repos they are explicitly given access to")
+	lockCmd.Flags().IntVar(&lockOpts.maxRepoCreation, "max-repo-creation", 0, "The maximum number of repos the participants can create, -1 for the global default")
+
+	return lockCmd
+}
+
+//NewUnlockWorkshopCommand instantiates the new instance of the UnlockWorkshopCommand
+func NewUnlockWorkshopCommand() *cobra.Command {
+	unlockOpts := &LockWorkshopOptions{
+		unlock:          true,
+		maxRepoCreation: -1,
+	}
+
+	unlockCmd := &cobra.Command{
+		Use:     "unlock-workshop",
+		Short:   "Unlock the participant accounts",
+		Example: unlockWorkshopCommandExample,
+		RunE:    unlockOpts.Execute,
+		PreRunE: unlockOpts.Validate,
+	}
+
+	unlockOpts.AddFlags(unlockCmd)
+
+	return unlockCmd
+}
+
+// AddFlags implements Command
+func (opts *LockWorkshopOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+}
+
+// Validate implements Command
+func (opts *LockWorkshopOptions) Validate(cmd *cobra.Command, args []string) error {
+	if opts.maxRepoCreation < -1 {
+		return fmt.Errorf("max-repo-creation must be -1 or greater")
+	}
+	return nil
+}
+
+// Execute implements Command
+func (opts *LockWorkshopOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	if opts.ifExpired {
+		if workshopOpts.ExpiresAt == nil {
+			log.Infoln("Workshop has no expiresAt, nothing to lock")
+			return nil
+		}
+		if time.Now().Before(*workshopOpts.ExpiresAt) {
+			log.Infof("Workshop expires at %s, nothing to lock", workshopOpts.ExpiresAt)
+			return nil
+		}
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	giteaUsers := workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		userName := participantUserName(i)
+		resp, err := c.AdminEditUser(userName, opts.editUserOption(userName))
+		if err != nil {
+			if isNotFound(resp) {
+				log.Warnf("User %s does not exist, skipping", userName)
+				continue
+			}
+			return err
+		}
+		if opts.unlock {
+			log.Infof("Unlocked user %s", userName)
+		} else {
+			log.Infof("Locked user %s", userName)
+		}
+	}
+
+	return nil
+}
+
+//editUserOption returns the Gitea user settings that lock or unlock the user
+func (opts *LockWorkshopOptions) editUserOption(userName string) gitea.EditUserOption {
+	prohibitLogin := opts.prohibitLogin
+	restricted := opts.restricted
+	maxRepoCreation := opts.maxRepoCreation
+	return gitea.EditUserOption{
+		//required by the Gitea API, the participants are local users
+		LoginName:       userName,
+		ProhibitLogin:   &prohibitLogin,
+		Restricted:      &restricted,
+		MaxRepoCreation: &maxRepoCreation,
+	}
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+//fakeAdminEditUser records the settings the users were edited with
+func fakeAdminEditUser(t *testing.T, edited map[string]gitea.EditUserOption) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("Expecting PATCH but got %s", r.Method)
+		}
+		var opt gitea.EditUserOption
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+			t.Errorf("%v", err)
+		}
+		edited[opt.LoginName] = opt
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestLockAndUnlockWorkshop(t *testing.T) {
+	edited := make(map[string]gitea.EditUserOption)
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/admin/users/user-01": fakeAdminEditUser(t, edited),
+		"/api/v1/admin/users/user-02": fakeAdminEditUser(t, edited),
+	})
+	workshopFile := writeWorkshopFile(t, s.URL)
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"lock-workshop", "-f", workshopFile, "--restricted=false"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	yes, no, zero := true, false, 0
+	expected := gitea.EditUserOption{LoginName: "user-02", ProhibitLogin: &yes, Restricted: &no, MaxRepoCreation: &zero}
+	if !reflect.DeepEqual(expected, edited["user-02"]) || len(edited) != 2 {
+		t.Errorf("Expecting user-01 and user-02 to be locked but got %v", edited)
+	}
+
+	rootCmd = NewRootCommand()
+	rootCmd.SetArgs([]string{"unlock-workshop", "-f", workshopFile})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	unlimited := -1
+	expected = gitea.EditUserOption{LoginName: "user-01", ProhibitLogin: &no, Restricted: &no, MaxRepoCreation: &unlimited}
+	if !reflect.DeepEqual(expected, edited["user-01"]) {
+		t.Errorf("Expecting user-01 to be unlocked but got %v", edited["user-01"])
+	}
+}
+
+func TestLockWorkshopIfExpired(t *testing.T) {
+	edited := make(map[string]gitea.EditUserOption)
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/admin/users/user-01": fakeAdminEditUser(t, edited),
+		"/api/v1/admin/users/user-02": fakeAdminEditUser(t, edited),
+	})
+
+	expiresAt := time.Now().Add(time.Hour)
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"lock-workshop", "--if-expired", "-f", writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.ExpiresAt = &expiresAt
+	})})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(edited) != 0 {
+		t.Errorf("Expecting no users to be locked before the workshop expires but got %v", edited)
+	}
+
+	expiresAt = time.Now().Add(-time.Hour)
+	rootCmd = NewRootCommand()
+	rootCmd.SetArgs([]string{"lock-workshop", "--if-expired", "-f", writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.ExpiresAt = &expiresAt
+	})})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(edited) != 2 {
+		t.Errorf("Expecting all the users to be locked after the workshop expired but got %v", edited)
+	}
+}
@@ -0,0 +1,397 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+//defaultImageRepo is the image repository the release images are published to
+const defaultImageRepo = "docker.io/kameshsampath/drone-tutorial-gitea-helper"
+
+//setupName is the name of the service account, RBAC, config map and job setting up the workshop
+const setupName = "gitea-configurer"
+
+//ManifestsOptions the options to generate the Kubernetes manifests that set up a workshop
+type ManifestsOptions struct {
+	configFile string
+	namespace  string
+	image      string
+	output     string
+	shardCount int
+}
+
+// ManifestsOptions implements Interface
+var _ Command = (*ManifestsOptions)(nil)
+
+var manifestsCommandExample = fmt.Sprintf(`
+  # Generate the manifests setting up the workshop in the namespace drone
+  %[1]s generate manifests --workshop-file workshop.yaml | kubectl apply -f -
+  # Generate the manifests in to a file for the namespace workshop-a using a custom image
+  %[1]s generate manifests -f workshop.yaml -n workshop-a -i registry.example.com/gitea-helper:v0.3.0 -o install.yaml
+  # Generate an Indexed Job setting up the participants in 4 parallel shards
+  %[1]s generate manifests -f workshop.yaml --shard-count 4
+`, ExamplePrefix())
+
+//NewManifestsCommand instantiates the new instance of the ManifestsCommand
+func NewManifestsCommand() *cobra.Command {
+	manifestsOpts := &ManifestsOptions{}
+
+	manifestsCmd := &cobra.Command{
+		Use:     "manifests",
+		Short:   "Generate the namespace, RBAC, config map and job that set up the workshop",
+		Example: manifestsCommandExample,
+		RunE:    manifestsOpts.Execute,
+		PreRunE: manifestsOpts.Validate,
+	}
+
+	manifestsOpts.AddFlags(manifestsCmd)
+
+	return manifestsCmd
+}
+
+// AddFlags implements Command
+func (opts *ManifestsOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "drone", "The namespace to run the setup job in")
+	cmd.Flags().StringVarP(&opts.image, "image", "i", defaultImage(), "The image of the setup job")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "The file to write the manifests to, defaults to the standard output")
+	cmd.Flags().IntVar(&opts.shardCount, "shard-count", 1, "The number of pods of the Indexed Job setting up the participants in parallel")
+}
+
+// Validate implements Command
+func (opts *ManifestsOptions) Validate(cmd *cobra.Command, args []string) error {
+	if opts.namespace == "" {
+		return fmt.Errorf("the namespace of the setup job is empty")
+	}
+	if opts.image == "" {
+		return fmt.Errorf("the image of the setup job is empty")
+	}
+	if opts.shardCount < 1 {
+		return fmt.Errorf("the shard count must be at least 1 but is %d", opts.shardCount)
+	}
+	return nil
+}
+
+// Execute implements Command
+func (opts *ManifestsOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+	config, err := ioutil.ReadFile(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	objects, err := opts.manifests(workshopOpts, string(config))
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = cmd.OutOrStdout()
+	if opts.output != "" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return writeManifests(out, objects)
+}
+
+//defaultImage returns the release image of the version of the binary
+func defaultImage() string {
+	if Version == "" {
+		return defaultImageRepo + ":latest"
+	}
+	return fmt.Sprintf("%s:%s", defaultImageRepo, Version)
+}
+
+//manifests returns the Kubernetes objects that set up the workshop of the config
+func (opts *ManifestsOptions) manifests(workshopOpts *WorkshopOptions, config string) ([]runtime.Object, error) {
+	permissions, err := workshopOpts.namespacePermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []runtime.Object{
+		namespace(opts.namespace, nil),
+		&apiv1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: setupObjectMeta(opts.namespace),
+		},
+	}
+
+	var namespaces, participantNamespaces []string
+	for ns, p := range permissions {
+		namespaces = append(namespaces, ns)
+		if p.participant {
+			participantNamespaces = append(participantNamespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	sort.Strings(participantNamespaces)
+
+	//the participant namespaces must exist for their roles, the setup job only reads them
+	for _, ns := range participantNamespaces {
+		objects = append(objects, namespace(ns, map[string]string{participantLabel: permissions[ns].participantName}))
+	}
+	if len(participantNamespaces) > 0 {
+		objects = append(objects, &rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: setupName, Labels: setupLabels()},
+			Rules: []rbacv1.PolicyRule{{
+				APIGroups:     []string{""},
+				Resources:     []string{"namespaces"},
+				ResourceNames: participantNamespaces,
+				Verbs:         []string{"get"},
+			}},
+		}, &rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: setupName, Labels: setupLabels()},
+			Subjects:   setupSubjects(opts.namespace),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: setupName},
+		})
+	}
+
+	for _, ns := range namespaces {
+		objects = append(objects, &rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+			ObjectMeta: setupObjectMeta(ns),
+			Rules:      permissions[ns].rules(),
+		}, &rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+			ObjectMeta: setupObjectMeta(ns),
+			Subjects:   setupSubjects(opts.namespace),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: setupName},
+		})
+	}
+
+	objects = append(objects, &apiv1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "workshop-config",
+			Namespace: opts.namespace,
+			Labels:    setupLabels(),
+		},
+		Data: map[string]string{"workshop.yaml": config},
+	}, opts.setupJob())
+
+	return objects, nil
+}
+
+//setupJob returns the job that sets up the workshop, an Indexed Job with a pod per shard
+//when the participants are split in to more than one shard
+func (opts *ManifestsOptions) setupJob() *batchv1.Job {
+	backoffLimit := int32(0)
+	args := []string{
+		"setup-workshop",
+		"--workshop-file=/config/workshop.yaml",
+	}
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{APIVersion: batchv1.SchemeGroupVersion.String(), Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "workshop-setup",
+			Namespace: opts.namespace,
+			Labels:    setupLabels(),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: apiv1.PodTemplateSpec{
+				Spec: apiv1.PodSpec{
+					ServiceAccountName: setupName,
+					RestartPolicy:      apiv1.RestartPolicyNever,
+					Containers: []apiv1.Container{{
+						Name:         setupName,
+						Image:        opts.image,
+						Args:         args,
+						VolumeMounts: []apiv1.VolumeMount{{Name: "workshop-config", MountPath: "/config"}},
+					}},
+					Volumes: []apiv1.Volume{{
+						Name: "workshop-config",
+						VolumeSource: apiv1.VolumeSource{
+							ConfigMap: &apiv1.ConfigMapVolumeSource{
+								LocalObjectReference: apiv1.LocalObjectReference{Name: "workshop-config"},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	if opts.shardCount > 1 {
+		shards := int32(opts.shardCount)
+		completionMode := batchv1.IndexedCompletion
+		//the backoff limit is for the whole job, the failed pods of all the shards count towards it and once
+		//it is exceeded the job fails, terminating the pods of the other shards. A retried pod reconciles the
+		//participants of its shard, completing those the failed pod half provisioned
+		backoffLimit = shards
+		job.Spec.CompletionMode = &completionMode
+		job.Spec.Completions = &shards
+		job.Spec.Parallelism = &shards
+		job.Spec.Template.Spec.Containers[0].Args = append(args, fmt.Sprintf("--shard-count=%d", opts.shardCount))
+	}
+
+	return job
+}
+
+//namespacePermission is what the setup job does in a namespace
+type namespacePermission struct {
+	secrets         bool
+	serviceAccounts bool
+	//participant is set for the namespace of a participant, where the service account is bound to the edit role
+	participant     bool
+	participantName string
+	tokens          bool
+	drone           bool
+}
+
+//namespacePermissions returns what the setup job does in each namespace of the workshop
+func (opts *WorkshopOptions) namespacePermissions() (map[string]*namespacePermission, error) {
+	giteaUsers := opts.GiteaUsers
+	permissions := make(map[string]*namespacePermission)
+	permission := func(ns string) *namespacePermission {
+		if ns == "" {
+			ns = "default"
+		}
+		if _, ok := permissions[ns]; !ok {
+			permissions[ns] = &namespacePermission{}
+		}
+		return permissions[ns]
+	}
+
+	if giteaUsers.AddKubernetesSecret && giteaUsers.SharedOAuthApps {
+		permission(giteaUsers.SecretNamespace).secrets = true
+	}
+
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		p := giteaUsers.participant(i)
+
+		if giteaUsers.AddKubernetesSecret && !giteaUsers.SharedOAuthApps {
+			oAuthApps, err := opts.participantOAuthApps(p, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range oAuthApps {
+				permission(o.namespace).secrets = true
+			}
+		}
+
+		if a := giteaUsers.ArgoCDRepoSecrets; a != nil {
+			ns, err := a.namespace(p)
+			if err != nil {
+				return nil, err
+			}
+			permission(ns).secrets = true
+		}
+
+		if tk := giteaUsers.TektonGitSecrets; tk != nil {
+			ns, _, err := tk.serviceAccount(p)
+			if err != nil {
+				return nil, err
+			}
+			permission(ns).secrets = true
+			permission(ns).serviceAccounts = true
+		}
+
+		if ns := giteaUsers.ParticipantNamespaces; ns != nil {
+			pp := permission(p.Namespace)
+			pp.participant, pp.participantName, pp.serviceAccounts = true, p.UserName, true
+			pp.tokens = ns.Kubeconfig != nil
+			pp.drone = giteaUsers.DroneServers != nil
+		}
+	}
+
+	return permissions, nil
+}
+
+//rules returns the least privilege rules of the setup job in the namespace
+func (np *namespacePermission) rules() []rbacv1.PolicyRule {
+	upsert := []string{"get", "create", "update"}
+	var rules []rbacv1.PolicyRule
+	if np.secrets || np.drone {
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: upsert})
+	}
+	if np.serviceAccounts {
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, Verbs: upsert})
+	}
+	if np.tokens {
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"serviceaccounts/token"}, Verbs: []string{"create"}})
+	}
+	if np.participant {
+		rules = append(rules,
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"resourcequotas", "limitranges"}, Verbs: upsert},
+			rbacv1.PolicyRule{APIGroups: []string{rbacv1.GroupName}, Resources: []string{"rolebindings"}, Verbs: upsert},
+			//binding the edit role without holding all its permissions
+			rbacv1.PolicyRule{APIGroups: []string{rbacv1.GroupName}, Resources: []string{"clusterroles"}, ResourceNames: []string{"edit"}, Verbs: []string{"bind"}},
+		)
+	}
+	if np.drone {
+		rules = append(rules,
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: upsert},
+			rbacv1.PolicyRule{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: upsert},
+			rbacv1.PolicyRule{APIGroups: []string{networkingv1.GroupName}, Resources: []string{"ingresses"}, Verbs: upsert},
+		)
+	}
+	return rules
+}
+
+//namespace returns the namespace with the labels
+func namespace(name string, labels map[string]string) *apiv1.Namespace {
+	return &apiv1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+	}
+}
+
+//setupLabels returns the labels of the objects setting up the workshop
+func setupLabels() map[string]string {
+	return map[string]string{"app.kubernetes.io/name": "drone-tutorial-gitea-helper"}
+}
+
+//setupObjectMeta returns the metadata of the object setting up the workshop in the namespace
+func setupObjectMeta(namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: setupName, Namespace: namespace, Labels: setupLabels()}
+}
+
+//setupSubjects returns the service account of the setup job
+func setupSubjects(namespace string) []rbacv1.Subject {
+	return []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: setupName, Namespace: namespace}}
+}
+
+//writeManifests writes the objects as a multi document YAML
+func writeManifests(w io.Writer, objects []runtime.Object) error {
+	var b bytes.Buffer
+	for i, o := range objects {
+		y, err := yaml.Marshal(o)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString("---\n")
+		}
+		b.Write(y)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
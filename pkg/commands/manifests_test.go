@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateManifests(t *testing.T) {
+	workshopFile := writeWorkshopFile(t, "http://gitea-127.0.0.1.sslip.io:30950", func(o *WorkshopOptions) {
+		o.GiteaUsers.AddKubernetesSecret = true
+		o.GiteaUsers.OAuthAppName = "drone"
+		o.GiteaUsers.OAuthRedirectURI = "http://drone-127.0.0.1.sslip.io:30980"
+		o.GiteaUsers.ArgoCDRepoSecrets = &ArgoCDRepoSecrets{}
+		o.GiteaUsers.ParticipantNamespaces = &ParticipantNamespaces{Kubeconfig: &ParticipantKubeconfig{}}
+	})
+
+	var out bytes.Buffer
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"generate", "manifests", "-f", workshopFile, "-n", "workshop", "-i", "example.com/gitea-helper:dev"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	type object struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	var kinds []string
+	roles := make(map[string]rbacv1.Role)
+	for _, doc := range strings.Split(out.String(), "---\n") {
+		var o object
+		if err := yaml.Unmarshal([]byte(doc), &o); err != nil {
+			t.Fatalf("%v", err)
+		}
+		kinds = append(kinds, o.Kind+"/"+o.Metadata.Namespace+"/"+o.Metadata.Name)
+		if o.Kind == "Role" {
+			var r rbacv1.Role
+			if err := yaml.Unmarshal([]byte(doc), &r); err != nil {
+				t.Fatalf("%v", err)
+			}
+			roles[r.Namespace] = r
+		}
+	}
+
+	expected := []string{
+		"Namespace//workshop",
+		"ServiceAccount/workshop/gitea-configurer",
+		"Namespace//user-01",
+		"Namespace//user-02",
+		"ClusterRole//gitea-configurer",
+		"ClusterRoleBinding//gitea-configurer",
+		"Role/argocd/gitea-configurer",
+		"RoleBinding/argocd/gitea-configurer",
+		"Role/user-01/gitea-configurer",
+		"RoleBinding/user-01/gitea-configurer",
+		"Role/user-02/gitea-configurer",
+		"RoleBinding/user-02/gitea-configurer",
+		"ConfigMap/workshop/workshop-config",
+		"Job/workshop/workshop-setup",
+	}
+	if !reflect.DeepEqual(expected, kinds) {
+		t.Errorf("Expecting the objects %v but got %v", expected, kinds)
+	}
+
+	if rules := roles["argocd"].Rules; len(rules) != 1 || rules[0].Resources[0] != "secrets" {
+		t.Errorf("Expecting the role in argocd to only manage secrets but got %v", rules)
+	}
+	var resources []string
+	for _, r := range roles["user-01"].Rules {
+		resources = append(resources, r.Resources...)
+	}
+	expectedResources := []string{"secrets", "serviceaccounts", "serviceaccounts/token", "resourcequotas", "limitranges", "rolebindings", "clusterroles"}
+	if !reflect.DeepEqual(expectedResources, resources) {
+		t.Errorf("Expecting the role in user-01 to manage %v but got %v", expectedResources, resources)
+	}
+	if !strings.Contains(out.String(), "image: example.com/gitea-helper:dev") {
+		t.Errorf("Expecting the setup job to use the image example.com/gitea-helper:dev")
+	}
+}
+
+func TestGenerateManifestsIndexedJob(t *testing.T) {
+	opts := &ManifestsOptions{namespace: "drone", image: "example.com/gitea-helper:dev", shardCount: 4}
+	job := opts.setupJob()
+	if job.Spec.CompletionMode == nil || *job.Spec.CompletionMode != batchv1.IndexedCompletion {
+		t.Fatalf("Expecting an Indexed Job but got %v", job.Spec.CompletionMode)
+	}
+	if *job.Spec.Completions != 4 || *job.Spec.Parallelism != 4 {
+		t.Errorf("Expecting a pod per shard but got %d completions and %d parallelism", *job.Spec.Completions, *job.Spec.Parallelism)
+	}
+	expected := []string{"setup-workshop", "--workshop-file=/config/workshop.yaml", "--shard-count=4"}
+	if args := job.Spec.Template.Spec.Containers[0].Args; !reflect.DeepEqual(expected, args) {
+		t.Errorf("Expecting the args %v but got %v", expected, args)
+	}
+
+	opts.shardCount = 1
+	if job := opts.setupJob(); job.Spec.CompletionMode != nil || *job.Spec.BackoffLimit != 0 {
+		t.Errorf("Expecting a single shard to be a non indexed job without retries")
+	}
+}
+
+func TestNamespacePermissionsSharedOAuthApps(t *testing.T) {
+	workshopOpts := &WorkshopOptions{GiteaUsers: GiteaUser{
+		From:                1,
+		To:                  3,
+		AddKubernetesSecret: true,
+		SharedOAuthApps:     true,
+		OAuthAppName:        "drone",
+		OAuthRedirectURI:    "http://drone-127.0.0.1.sslip.io:30980",
+	}}
+	permissions, err := workshopOpts.namespacePermissions()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(permissions) != 1 || permissions["default"] == nil || !permissions["default"].secrets {
+		t.Errorf("Expecting only the secrets in default to be managed for the shared oAuth apps but got %v", permissions)
+	}
+}
@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+)
+
+//OAuthApp is an oAuth application created for each participant e.g. for Drone, Argo CD, Grafana or code-server,
+//the name, redirect URIs and secret name are Go templates rendered with the Participant e.g. {{ .UserName }}
+type OAuthApp struct {
+	Name         string   `yaml:"name"`
+	RedirectURIs []string `yaml:"redirectURIs"`
+	//ConfidentialClient makes the oAuth application a confidential client, defaults to true
+	ConfidentialClient *bool `yaml:"confidentialClient,omitempty"`
+	//SecretName is the name of the Kubernetes secret of the application, defaults to <name>-secret
+	SecretName string `yaml:"secretName,omitempty"`
+	//Profile is the secret profile of the CI server or tool using the application, defaults to drone
+	Profile string `yaml:"profile,omitempty"`
+	//Keys are the keys of the Kubernetes secret of the application, overriding the keys of the profile
+	Keys *SecretKeys `yaml:"keys,omitempty"`
+}
+
+//SecretKeys are the keys of the Kubernetes secret that holds the client id and secret of an oAuth application
+type SecretKeys struct {
+	ClientID     string `yaml:"clientID"`
+	ClientSecret string `yaml:"clientSecret"`
+	//RandomSecret is the key of a random secret generated along e.g. the DRONE_RPC_SECRET, none when empty
+	RandomSecret string `yaml:"randomSecret,omitempty"`
+}
+
+//droneSecretKeys are the secret keys the Drone server reads its Gitea oAuth application settings from
+var droneSecretKeys = SecretKeys{
+	ClientID:     "DRONE_GITEA_CLIENT_ID",
+	ClientSecret: "DRONE_GITEA_CLIENT_SECRET",
+	RandomSecret: "DRONE_RPC_SECRET",
+}
+
+//secretProfile is the layout of the oAuth application secret a CI server or tool expects
+type secretProfile struct {
+	keys   SecretKeys
+	labels map[string]string
+}
+
+//secretProfiles are the built-in secret profiles by their name
+var secretProfiles = map[string]secretProfile{
+	"drone": {keys: droneSecretKeys},
+	"woodpecker": {keys: SecretKeys{
+		ClientID:     "WOODPECKER_GITEA_CLIENT",
+		ClientSecret: "WOODPECKER_GITEA_SECRET",
+		RandomSecret: "WOODPECKER_AGENT_SECRET",
+	}},
+	//Argo CD resolves the $<secret>:<key> references of its dex config only from the secrets labelled as part of it
+	"argocd-dex": {
+		keys: SecretKeys{
+			ClientID:     "dex.gitea.clientID",
+			ClientSecret: "dex.gitea.clientSecret",
+		},
+		labels: map[string]string{"app.kubernetes.io/part-of": "argocd"},
+	},
+	"generic": {keys: SecretKeys{
+		ClientID:     "CLIENT_ID",
+		ClientSecret: "CLIENT_SECRET",
+	}},
+}
+
+//secretProfileNames returns the names of the built-in secret profiles
+func secretProfileNames() []string {
+	names := make([]string, 0, len(secretProfiles))
+	for name := range secretProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//secretLayout returns the secret keys and labels of the profile, defaults to the drone profile,
+//the keys of the profile are replaced by the custom keys when set
+func secretLayout(profile string, keys *SecretKeys) (SecretKeys, map[string]string, error) {
+	if profile == "" {
+		profile = "drone"
+	}
+	sp, ok := secretProfiles[profile]
+	if !ok {
+		return SecretKeys{}, nil, fmt.Errorf("unknown secret profile %q, must be one of %s", profile, strings.Join(secretProfileNames(), ", "))
+	}
+	if keys != nil {
+		if keys.ClientID == "" || keys.ClientSecret == "" {
+			return SecretKeys{}, nil, fmt.Errorf("the custom secret keys require both the clientID and clientSecret keys")
+		}
+		return *keys, sp.labels, nil
+	}
+	return sp.keys, sp.labels, nil
+}
+
+//oAuthAppList returns the oAuth applications of the participants, when oAuthApps is not set
+//the Drone application is made from oAuthAppName, oAuthRedirectURI(s) and oAuthConfidentialClient
+func (u GiteaUser) oAuthAppList() []OAuthApp {
+	if len(u.OAuthApps) > 0 {
+		return u.OAuthApps
+	}
+	if u.OAuthAppName == "" {
+		return nil
+	}
+	redirectURIs := u.OAuthRedirectURIs
+	if len(redirectURIs) == 0 {
+		redirectURIs = []string{fmt.Sprintf("%s/login", u.OAuthRedirectURI)}
+	}
+	name := u.OAuthAppName + "-{{ .UserName }}"
+	if u.SharedOAuthApps {
+		name = u.OAuthAppName
+	}
+	return []OAuthApp{{
+		Name:               name,
+		RedirectURIs:       redirectURIs,
+		ConfidentialClient: u.OAuthConfidentialClient,
+		SecretName:         u.SecretName,
+		Profile:            u.SecretProfile,
+		Keys:               u.SecretKeys,
+	}}
+}
+
+//oAuthAppOwner returns the participant that owns the oAuth applications,
+//nil when the applications are shared by all the participants and owned by the admin user
+func (u GiteaUser) oAuthAppOwner(p *Participant) *Participant {
+	if u.SharedOAuthApps {
+		return nil
+	}
+	return p
+}
+
+//validateOAuthApps checks the templates of the oAuth applications render for a participant
+func (u GiteaUser) validateOAuthApps() error {
+	owner := u.oAuthAppOwner(u.participant(u.From))
+	for _, a := range u.oAuthAppList() {
+		if _, err := a.options(owner); err != nil {
+			if owner == nil {
+				return fmt.Errorf("invalid shared oAuth application %s, its templates can't refer to the participant, %v", a.Name, err)
+			}
+			return fmt.Errorf("invalid oAuth application %s, %v", a.Name, err)
+		}
+	}
+	return nil
+}
+
+//createSharedOAuthApps creates the oAuth applications shared by all the participants owned by the admin user,
+//the existing applications are left as they are as updating them would regenerate their client secrets
+func (opts *WorkshopOptions) createSharedOAuthApps(c *gitea.Client, kubeconfig string) error {
+	oAuthApps, err := opts.participantOAuthApps(nil, kubeconfig)
+	if err != nil {
+		return err
+	}
+	for _, oauthOpts := range oAuthApps {
+		o, err := findOAuthApp(c, oauthOpts.oAuthAppName)
+		if err != nil {
+			return err
+		}
+		if o != nil {
+			log.Infof("Shared oAuth application %s already exists", o.Name)
+			continue
+		}
+		if _, err := oauthOpts.createOAuthApp(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//options renders the oAuth application of the participant in to the options to manage it
+func (a OAuthApp) options(p *Participant) (*OAuthAppOptions, error) {
+	name, err := renderTemplate(a.Name, p)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("the name of the oAuth application is empty")
+	}
+
+	var redirectURIs []string
+	for _, text := range a.RedirectURIs {
+		redirectURI, err := renderTemplate(text, p)
+		if err != nil {
+			return nil, err
+		}
+		redirectURIs = append(redirectURIs, redirectURI)
+	}
+
+	secretName := fmt.Sprintf("%s-secret", name)
+	if a.SecretName != "" {
+		if secretName, err = renderTemplate(a.SecretName, p); err != nil {
+			return nil, err
+		}
+	}
+
+	secretKeys, secretLabels, err := secretLayout(a.Profile, a.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthAppOptions{
+		oAuthAppName:       name,
+		appRedirectURLs:    redirectURIs,
+		confidentialClient: a.ConfidentialClient == nil || *a.ConfidentialClient,
+		secretName:         secretName,
+		secretKeys:         secretKeys,
+		secretLabels:       secretLabels,
+	}, nil
+}
+
+//renderTemplate renders the Go template text with the data
+func renderTemplate(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestOAuthAppListDefaults(t *testing.T) {
+	u := GiteaUser{OAuthAppName: "demo-oauth", OAuthRedirectURI: "http://drone-127.0.0.1.sslip.io:30980"}
+	p := u.participant(3)
+
+	apps := u.oAuthAppList()
+	if len(apps) != 1 {
+		t.Fatalf("Expecting the Drone oAuth application but got %v", apps)
+	}
+	o, err := apps[0].options(p)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if o.oAuthAppName != "demo-oauth-user-03" || o.secretName != "demo-oauth-user-03-secret" || !o.confidentialClient || o.secretKeys != droneSecretKeys {
+		t.Errorf("Expecting the Drone oAuth application demo-oauth-user-03 but got %v", o)
+	}
+}
+
+func TestOAuthAppRedirectURIs(t *testing.T) {
+	u := GiteaUser{OAuthAppName: "demo-oauth", OAuthRedirectURI: "http://drone-127.0.0.1.sslip.io:30980"}
+	p := u.participant(3)
+
+	o, err := u.oAuthAppList()[0].options(p)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if expected := []string{"http://drone-127.0.0.1.sslip.io:30980/login"}; !reflect.DeepEqual(expected, o.appRedirectURLs) {
+		t.Errorf("Expecting the default redirect URIs %v but got %v", expected, o.appRedirectURLs)
+	}
+
+	u.OAuthRedirectURIs = []string{
+		"http://drone-127.0.0.1.sslip.io:30980/login",
+		"https://drone-{{ .UserName }}.example.com/login",
+	}
+	if o, err = u.oAuthAppList()[0].options(p); err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := []string{"http://drone-127.0.0.1.sslip.io:30980/login", "https://drone-user-03.example.com/login"}
+	if !reflect.DeepEqual(expected, o.appRedirectURLs) {
+		t.Errorf("Expecting the redirect URIs %v but got %v", expected, o.appRedirectURLs)
+	}
+
+	u.OAuthRedirectURIs = []string{"https://drone-{{ .Unknown }}.example.com/login"}
+	if _, err := u.oAuthAppList()[0].options(p); err == nil {
+		t.Errorf("Expecting the redirect URI referring to an unknown field to be invalid")
+	}
+}
+
+func TestOAuthAppList(t *testing.T) {
+	public := false
+	u := GiteaUser{
+		From:                1,
+		To:                  2,
+		AddKubernetesSecret: true,
+		OAuthAppName:        "ignored",
+		OAuthApps: []OAuthApp{
+			{Name: "drone-{{ .UserName }}", RedirectURIs: []string{"http://drone/login"}},
+			{
+				Name:               "argocd-{{ .UserName }}",
+				RedirectURIs:       []string{"https://argocd-{{ .UserName }}.example.com/api/dex/callback"},
+				ConfidentialClient: &public,
+				SecretName:         "argocd-dex-{{ .Index }}",
+				Keys:               &SecretKeys{ClientID: "dex.gitea.clientID", ClientSecret: "dex.gitea.clientSecret"},
+			},
+		},
+	}
+
+	p := u.participant(2)
+	if p.OAuthAppName != "drone-user-02" || p.SecretName != "drone-user-02-secret" {
+		t.Errorf("Expecting the first oAuth application to be the one of the participant but got %s %s", p.OAuthAppName, p.SecretName)
+	}
+
+	o, err := u.oAuthAppList()[1].options(p)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := &OAuthAppOptions{
+		oAuthAppName:    "argocd-user-02",
+		appRedirectURLs: []string{"https://argocd-user-02.example.com/api/dex/callback"},
+		secretName:      "argocd-dex-2",
+		secretKeys:      SecretKeys{ClientID: "dex.gitea.clientID", ClientSecret: "dex.gitea.clientSecret"},
+	}
+	if !reflect.DeepEqual(expected, o) {
+		t.Errorf("Expecting the oAuth application %v but got %v", expected, o)
+	}
+}
+
+func TestValidateOAuthApps(t *testing.T) {
+	u := GiteaUser{From: 1, To: 2, OAuthApps: []OAuthApp{{Name: "grafana-{{ .Missing }}"}}}
+	if err := u.validateOAuthApps(); err == nil {
+		t.Errorf("Expecting the oAuth application with an invalid name template to fail validation")
+	}
+}
+
+func TestSetupWorkshopSharedOAuthApps(t *testing.T) {
+	var created []string
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/users/": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"/api/v1/admin/users": func(w http.ResponseWriter, r *http.Request) {
+			var u map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+				t.Errorf("%v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"login":%q}`, u["username"])
+		},
+		"/api/v1/user/applications/oauth2": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+			var o oAuth2Option
+			if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+				t.Errorf("%v", err)
+			}
+			created = append(created, fmt.Sprintf("%s:%s", r.Header.Get("Sudo"), o.Name))
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"id":1,"name":%q}`, o.Name)
+		},
+		"/api/v1/repos/": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"/api/v1/repos/migrate": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"name":"jar-stack"}`)
+		},
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"setup-workshop", "-f", writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.GiteaUsers.OAuthAppName = "workshop-drone"
+		o.GiteaUsers.OAuthRedirectURI = "https://drone.example.com"
+		o.GiteaUsers.SharedOAuthApps = true
+	}), "--skip-pipeline-validation"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if expected := []string{":workshop-drone"}; !reflect.DeepEqual(expected, created) {
+		t.Errorf("Expecting only the shared oAuth application owned by the admin user to be created but got %v", created)
+	}
+}
+
+func TestValidateSharedOAuthApps(t *testing.T) {
+	u := GiteaUser{From: 1, To: 2, SharedOAuthApps: true, OAuthApps: []OAuthApp{{Name: "drone-{{ .UserName }}"}}}
+	if err := u.validateOAuthApps(); err == nil {
+		t.Errorf("Expecting the shared oAuth application with a per participant name to fail validation")
+	}
+	u.OAuthApps[0].Name = "drone"
+	if err := u.validateOAuthApps(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestSecretLayout(t *testing.T) {
+	keys, labels, err := secretLayout("", nil)
+	if err != nil || keys != droneSecretKeys || labels != nil {
+		t.Errorf("Expecting the drone secret keys by default but got %v %v %v", keys, labels, err)
+	}
+
+	keys, labels, err = secretLayout("argocd-dex", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if keys.ClientSecret != "dex.gitea.clientSecret" || labels["app.kubernetes.io/part-of"] != "argocd" {
+		t.Errorf("Expecting the argocd-dex secret keys and labels but got %v %v", keys, labels)
+	}
+
+	custom := &SecretKeys{ClientID: "GF_CLIENT_ID", ClientSecret: "GF_CLIENT_SECRET"}
+	if keys, _, err = secretLayout("generic", custom); err != nil || keys != *custom {
+		t.Errorf("Expecting the custom secret keys %v but got %v %v", custom, keys, err)
+	}
+
+	if _, _, err = secretLayout("jenkins", nil); err == nil {
+		t.Errorf("Expecting an unknown secret profile to fail")
+	}
+	if _, _, err = secretLayout("generic", &SecretKeys{ClientID: "ID"}); err == nil {
+		t.Errorf("Expecting custom secret keys without the client secret key to fail")
+	}
+}
+
+func TestCreateOAuthAppSecretFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--secret-profile", "jenkins"},
+		{"--secret-key", "clientID=ID,clientSecret=SECRET,token=TOKEN"},
+	} {
+		rootCmd := NewRootCommand()
+		rootCmd.SetArgs(append([]string{"oauthapp", "create", "-a", "my-app", "-s", "-n", "default"}, args...))
+		if err := rootCmd.Execute(); err == nil {
+			t.Errorf("Expecting the secret flags %v to fail validation", args)
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//ListOAuthAppsOptions the options to list the oAuth applications
+type ListOAuthAppsOptions struct {
+	OAuthAppOptions
+	output string
+}
+
+//GetOAuthAppOptions the options to get an oAuth application
+type GetOAuthAppOptions struct {
+	OAuthAppOptions
+	output string
+}
+
+//UpdateOAuthAppOptions the options to update the redirect URIs of an oAuth application
+type UpdateOAuthAppOptions struct {
+	OAuthAppOptions
+}
+
+//DeleteOAuthAppOptions the options to delete an oAuth application
+type DeleteOAuthAppOptions struct {
+	OAuthAppOptions
+}
+
+//RotateOAuthAppOptions the options to regenerate the client secret of an oAuth application
+type RotateOAuthAppOptions struct {
+	OAuthAppOptions
+}
+
+// The oauthapp commands implement Interface
+var (
+	_ Command = (*ListOAuthAppsOptions)(nil)
+	_ Command = (*GetOAuthAppOptions)(nil)
+	_ Command = (*UpdateOAuthAppOptions)(nil)
+	_ Command = (*DeleteOAuthAppOptions)(nil)
+	_ Command = (*RotateOAuthAppOptions)(nil)
+)
+
+var listOAuthAppsCommandExample = fmt.Sprintf(`
+  # List the oAuth applications of the admin user
+  %[1]s oauthapp list
+  # List the oAuth applications of user-01 as JSON
+  %[1]s oauthapp list --sudo user-01 -o json
+`, ExamplePrefix())
+
+var getOAuthAppCommandExample = fmt.Sprintf(`
+  # Get the oAuth application my-app
+  %[1]s oauthapp get -a my-app
+  # Get the oAuth application with id 3 of user-01
+  %[1]s oauthapp get --id 3 --sudo user-01
+`, ExamplePrefix())
+
+var updateOAuthAppCommandExample = fmt.Sprintf(`
+  # Update the redirect URLs of the oAuth application my-app and its kubernetes secret
+  %[1]s oauthapp update -a my-app -r http://drone.example.com/login,http://drone-127.0.0.1.sslip.io:30980/login -s -n my-namespace
+`, ExamplePrefix())
+
+var deleteOAuthAppCommandExample = fmt.Sprintf(`
+  # Delete the oAuth application my-app of user-01 and its kubernetes secret
+  %[1]s oauthapp delete -a my-app --sudo user-01 -s -n my-namespace
+`, ExamplePrefix())
+
+var rotateOAuthAppCommandExample = fmt.Sprintf(`
+  # Regenerate the client secret of the oAuth application my-app
+  %[1]s oauthapp rotate -a my-app
+  # Regenerate the client secret of the oAuth application my-app of user-01 and update its kubernetes secret
+  %[1]s oauthapp rotate -a my-app --sudo user-01 -s -n my-namespace
+`, ExamplePrefix())
+
+//NewOAuthAppCommand instantiates the new instance of the OAuthAppCommand that groups
+//the commands to manage the Gitea oAuth applications
+func NewOAuthAppCommand() *cobra.Command {
+	oAuthCmd := &cobra.Command{
+		Use:   "oauthapp",
+		Short: "Manage the Gitea oAuth applications",
+	}
+
+	oAuthCmd.AddCommand(NewCreateOAuthAppCommand())
+	oAuthCmd.AddCommand(NewListOAuthAppsCommand())
+	oAuthCmd.AddCommand(NewGetOAuthAppCommand())
+	oAuthCmd.AddCommand(NewUpdateOAuthAppCommand())
+	oAuthCmd.AddCommand(NewDeleteOAuthAppCommand())
+	oAuthCmd.AddCommand(NewRotateOAuthAppCommand())
+
+	return oAuthCmd
+}
+
+//NewListOAuthAppsCommand instantiates the new instance of the ListOAuthAppsCommand
+func NewListOAuthAppsCommand() *cobra.Command {
+	listOpts := &ListOAuthAppsOptions{}
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List the Gitea oAuth applications",
+		Example: listOAuthAppsCommandExample,
+		RunE:    listOpts.Execute,
+		PreRunE: listOpts.Validate,
+	}
+
+	listOpts.AddFlags(listCmd)
+
+	return listCmd
+}
+
+//NewGetOAuthAppCommand instantiates the new instance of the GetOAuthAppCommand
+func NewGetOAuthAppCommand() *cobra.Command {
+	getOpts := &GetOAuthAppOptions{}
+
+	getCmd := &cobra.Command{
+		Use:     "get",
+		Short:   "Get a Gitea oAuth application",
+		Example: getOAuthAppCommandExample,
+		RunE:    getOpts.Execute,
+		PreRunE: getOpts.Validate,
+	}
+
+	getOpts.AddFlags(getCmd)
+
+	return getCmd
+}
+
+//NewUpdateOAuthAppCommand instantiates the new instance of the UpdateOAuthAppCommand
+func NewUpdateOAuthAppCommand() *cobra.Command {
+	updateOpts := &UpdateOAuthAppOptions{}
+
+	updateCmd := &cobra.Command{
+		Use:     "update",
+		Short:   "Update the redirect URLs of a Gitea oAuth application",
+		Example: updateOAuthAppCommandExample,
+		RunE:    updateOpts.Execute,
+		PreRunE: updateOpts.Validate,
+	}
+
+	updateOpts.AddFlags(updateCmd)
+
+	return updateCmd
+}
+
+//NewDeleteOAuthAppCommand instantiates the new instance of the DeleteOAuthAppCommand
+func NewDeleteOAuthAppCommand() *cobra.Command {
+	deleteOpts := &DeleteOAuthAppOptions{}
+
+	deleteCmd := &cobra.Command{
+		Use:     "delete",
+		Short:   "Delete a Gitea oAuth application",
+		Example: deleteOAuthAppCommandExample,
+		RunE:    deleteOpts.Execute,
+		PreRunE: deleteOpts.Validate,
+	}
+
+	deleteOpts.AddFlags(deleteCmd)
+
+	return deleteCmd
+}
+
+//NewRotateOAuthAppCommand instantiates the new instance of the RotateOAuthAppCommand
+func NewRotateOAuthAppCommand() *cobra.Command {
+	rotateOpts := &RotateOAuthAppOptions{}
+
+	rotateCmd := &cobra.Command{
+		Use:     "rotate",
+		Short:   "Regenerate the client secret of a Gitea oAuth application",
+		Example: rotateOAuthAppCommandExample,
+		RunE:    rotateOpts.Execute,
+		PreRunE: rotateOpts.Validate,
+	}
+
+	rotateOpts.AddFlags(rotateCmd)
+
+	return rotateCmd
+}
+
+//addAppFlags adds the flags to identify an existing oAuth application by its name or id
+func (opts *OAuthAppOptions) addAppFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.oAuthAppName, "app-name", "a", "", "The Gitea oAuth Application Name")
+	cmd.Flags().Int64Var(&opts.id, "id", 0, "The Gitea oAuth Application ID, instead of the name")
+}
+
+//validateApp checks the oAuth application is identified by either its name or id
+func (opts *OAuthAppOptions) validateApp() error {
+	if opts.oAuthAppName == "" && opts.id == 0 {
+		return fmt.Errorf("require either the app-name or the id of the oAuth application")
+	}
+	if opts.oAuthAppName != "" && opts.id != 0 {
+		return fmt.Errorf("app-name and id are mutually exclusive")
+	}
+	return nil
+}
+
+//validateSecret checks the namespace is set when the Kubernetes secret has to be kept in sync
+func (opts *OAuthAppOptions) validateSecret() error {
+	if opts.addKubernetesSecret && opts.namespace == "" {
+		return fmt.Errorf("require namespace to sync the secret of the oAuth application")
+	}
+	return opts.resolveSecretLayout()
+}
+
+//findApp finds the oAuth application identified by the name or id,
+//the name of the options is set to the name of the application found
+func (opts *OAuthAppOptions) findApp(c *gitea.Client) (*gitea.Oauth2, error) {
+	if opts.id != 0 {
+		o, resp, err := c.GetOauth2(opts.id)
+		if err != nil {
+			if isNotFound(resp) {
+				return nil, fmt.Errorf("oAuth application with id %d not found", opts.id)
+			}
+			return nil, err
+		}
+		opts.oAuthAppName = o.Name
+		return o, nil
+	}
+
+	o, err := findOAuthApp(c, opts.oAuthAppName)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, fmt.Errorf("oAuth application %s not found", opts.oAuthAppName)
+	}
+	return o, nil
+}
+
+//updateApp updates the oAuth application with the redirect URIs of the options and syncs the Kubernetes secret,
+//Gitea regenerates the client secret whenever an application is updated
+func (opts *OAuthAppOptions) updateApp(o *gitea.Oauth2) (*gitea.Oauth2, error) {
+	o, err := opts.saveOAuth2(o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.addKubernetesSecret {
+		if err := opts.updateKubernetesSecret(o); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+// AddFlags implements Command
+func (opts *ListOAuthAppsOptions) AddFlags(cmd *cobra.Command) {
+	opts.addGiteaFlags(cmd)
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "The output format, one of text or json")
+}
+
+// Validate implements Command
+func (opts *ListOAuthAppsOptions) Validate(cmd *cobra.Command, args []string) error {
+	return validateOAuthAppsOutput(opts.output)
+}
+
+// Execute implements Command
+func (opts *ListOAuthAppsOptions) Execute(cmd *cobra.Command, args []string) error {
+	c, err := opts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	oAuthApps, err := listOAuthApps(c)
+	if err != nil {
+		return err
+	}
+
+	return writeOAuthApps(cmd.OutOrStdout(), opts.output, oAuthApps)
+}
+
+// AddFlags implements Command
+func (opts *GetOAuthAppOptions) AddFlags(cmd *cobra.Command) {
+	opts.addAppFlags(cmd)
+	opts.addGiteaFlags(cmd)
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "The output format, one of text or json")
+}
+
+// Validate implements Command
+func (opts *GetOAuthAppOptions) Validate(cmd *cobra.Command, args []string) error {
+	if err := opts.validateApp(); err != nil {
+		return err
+	}
+	return validateOAuthAppsOutput(opts.output)
+}
+
+// Execute implements Command
+func (opts *GetOAuthAppOptions) Execute(cmd *cobra.Command, args []string) error {
+	c, err := opts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	o, err := opts.findApp(c)
+	if err != nil {
+		return err
+	}
+
+	return writeOAuthApps(cmd.OutOrStdout(), opts.output, []*gitea.Oauth2{o})
+}
+
+// AddFlags implements Command
+func (opts *UpdateOAuthAppOptions) AddFlags(cmd *cobra.Command) {
+	opts.addAppFlags(cmd)
+	cmd.Flags().StringSliceVarP(&opts.appRedirectURLs, "app-redirect-url", "r", nil, "The Gitea oAuth Application Redirect URLs, repeat the flag or separate them by comma to set more than one")
+	if err := cmd.MarkFlagRequired("app-redirect-url"); err != nil {
+		log.Fatalf("Error marking flag 'app-redirect-url' as required %v", err)
+	}
+	opts.addConfidentialFlag(cmd)
+	opts.addGiteaFlags(cmd)
+	opts.addSecretFlags(cmd)
+}
+
+// Validate implements Command
+func (opts *UpdateOAuthAppOptions) Validate(cmd *cobra.Command, args []string) error {
+	if err := opts.validateApp(); err != nil {
+		return err
+	}
+	return opts.validateSecret()
+}
+
+// Execute implements Command
+func (opts *UpdateOAuthAppOptions) Execute(cmd *cobra.Command, args []string) error {
+	c, err := opts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	o, err := opts.findApp(c)
+	if err != nil {
+		return err
+	}
+
+	o, err = opts.updateApp(o)
+	if err != nil {
+		return err
+	}
+	log.Infof("Updated the redirect URLs of oAuth application %s, its client secret was regenerated", o.Name)
+
+	writeOAuthAppCredentials(cmd.OutOrStdout(), o)
+
+	return nil
+}
+
+// AddFlags implements Command
+func (opts *DeleteOAuthAppOptions) AddFlags(cmd *cobra.Command) {
+	opts.addAppFlags(cmd)
+	opts.addGiteaFlags(cmd)
+	opts.addSecretFlags(cmd)
+}
+
+// Validate implements Command
+func (opts *DeleteOAuthAppOptions) Validate(cmd *cobra.Command, args []string) error {
+	if err := opts.validateApp(); err != nil {
+		return err
+	}
+	return opts.validateSecret()
+}
+
+// Execute implements Command
+func (opts *DeleteOAuthAppOptions) Execute(cmd *cobra.Command, args []string) error {
+	c, err := opts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	o, err := opts.findApp(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.DeleteOauth2(o.ID); err != nil {
+		return err
+	}
+	log.Infof("Deleted oAuth application %s", o.Name)
+
+	if opts.addKubernetesSecret {
+		return opts.deleteKubernetesSecret()
+	}
+
+	return nil
+}
+
+// AddFlags implements Command
+func (opts *RotateOAuthAppOptions) AddFlags(cmd *cobra.Command) {
+	opts.addAppFlags(cmd)
+	opts.addGiteaFlags(cmd)
+	opts.addSecretFlags(cmd)
+}
+
+// Validate implements Command
+func (opts *RotateOAuthAppOptions) Validate(cmd *cobra.Command, args []string) error {
+	if err := opts.validateApp(); err != nil {
+		return err
+	}
+	return opts.validateSecret()
+}
+
+// Execute implements Command
+func (opts *RotateOAuthAppOptions) Execute(cmd *cobra.Command, args []string) error {
+	c, err := opts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	o, err := opts.findApp(c)
+	if err != nil {
+		return err
+	}
+
+	//rotating keeps the redirect URIs and the client type of the application
+	opts.appRedirectURLs = o.RedirectURIs
+	if opts.confidentialClient, err = opts.oAuth2Confidential(o.ID); err != nil {
+		return err
+	}
+	o, err = opts.updateApp(o)
+	if err != nil {
+		return err
+	}
+	log.Infof("Regenerated the client secret of oAuth application %s", o.Name)
+
+	writeOAuthAppCredentials(cmd.OutOrStdout(), o)
+
+	return nil
+}
+
+//validateOAuthAppsOutput checks the output format of the oAuth applications is supported
+func validateOAuthAppsOutput(output string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format %q, must be one of text or json", output)
+	}
+	return nil
+}
+
+//writeOAuthApps writes the oAuth applications to out in the format
+func writeOAuthApps(out io.Writer, format string, oAuthApps []*gitea.Oauth2) error {
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(oAuthApps)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCLIENT ID\tREDIRECT URIS\tCREATED")
+	for _, o := range oAuthApps {
+		fmt.Fprintln(w, strings.Join([]string{
+			strconv.FormatInt(o.ID, 10),
+			o.Name,
+			o.ClientID,
+			strings.Join(o.RedirectURIs, ","),
+			o.Created.Format("2006-01-02T15:04:05Z07:00"),
+		}, "\t"))
+	}
+	return w.Flush()
+}
+
+//writeOAuthAppCredentials writes the client id and the regenerated client secret of the oAuth application,
+//Gitea returns the client secret only when it is generated
+func writeOAuthAppCredentials(out io.Writer, o *gitea.Oauth2) {
+	fmt.Fprintf(out, "Client ID:     %s\n", o.ClientID)
+	fmt.Fprintf(out, "Client Secret: %s\n", o.ClientSecret)
+}
@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+//fakeOAuthApps serves the oAuth applications of the sudo user in pages of 50
+func fakeOAuthApps(t *testing.T, sudo string, count int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Sudo") != sudo {
+			t.Errorf("Expecting the oAuth applications to be managed as %q but got %q", sudo, r.Header.Get("Sudo"))
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":100,"name":"new-app"}`)
+			return
+		}
+		var page int
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		var apps []gitea.Oauth2
+		for i := (page-1)*50 + 1; i <= page*50 && i <= count; i++ {
+			apps = append(apps, gitea.Oauth2{ID: int64(i), Name: fmt.Sprintf("app-%d", i), RedirectURIs: []string{"http://drone/login"}})
+		}
+		if err := json.NewEncoder(w).Encode(apps); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+}
+
+func TestListOAuthApps(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/user/applications/oauth2": fakeOAuthApps(t, "user-01", 51),
+	})
+
+	var out bytes.Buffer
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"oauthapp", "list", "-g", s.URL, "--sudo", "user-01", "-o", "json"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var apps []gitea.Oauth2
+	if err := json.Unmarshal(out.Bytes(), &apps); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(apps) != 51 || apps[50].Name != "app-51" {
+		t.Errorf("Expecting the oAuth applications of all the pages to be listed but got %d", len(apps))
+	}
+}
+
+func TestRotateOAuthApp(t *testing.T) {
+	updated := oAuth2Option{ConfidentialClient: true}
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/user/applications/oauth2/7": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPatch {
+				if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+					t.Errorf("%v", err)
+				}
+				fmt.Fprint(w, `{"id":7,"name":"my-app","client_id":"cid","client_secret":"new-secret"}`)
+				return
+			}
+			fmt.Fprint(w, `{"id":7,"name":"my-app","client_id":"cid","redirect_uris":["http://drone/login"],"confidential_client":false}`)
+		},
+	})
+
+	var out bytes.Buffer
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"oauthapp", "rotate", "-g", s.URL, "--id", "7"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if updated.Name != "my-app" || len(updated.RedirectURIs) != 1 || updated.RedirectURIs[0] != "http://drone/login" {
+		t.Errorf("Expecting the oAuth application to be updated with its name and redirect URIs but got %v", updated)
+	}
+	if updated.ConfidentialClient {
+		t.Error("Expecting the public client to stay a public client")
+	}
+	if !strings.Contains(out.String(), "new-secret") {
+		t.Errorf("Expecting the regenerated client secret to be printed but got %s", out.String())
+	}
+}
+
+func TestDeleteOAuthApp(t *testing.T) {
+	deleted := false
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/user/applications/oauth2": fakeOAuthApps(t, "user-02", 3),
+		"/api/v1/user/applications/oauth2/2": func(w http.ResponseWriter, r *http.Request) {
+			deleted = r.Method == http.MethodDelete
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"oauthapp", "delete", "-g", s.URL, "--sudo", "user-02", "-a", "app-2"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !deleted {
+		t.Errorf("Expecting the oAuth application app-2 to be deleted")
+	}
+
+	rootCmd = NewRootCommand()
+	rootCmd.SetArgs([]string{"oauthapp", "delete", "-g", s.URL, "--sudo", "user-02", "-a", "missing"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Errorf("Expecting deleting a missing oAuth application to fail")
+	}
+}
+
+func TestCreateOAuthAppUpdatesExisting(t *testing.T) {
+	var updated gitea.CreateOauth2Option
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/user/applications/oauth2": fakeOAuthApps(t, "", 1),
+		"/api/v1/user/applications/oauth2/1": func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+				t.Errorf("%v", err)
+			}
+			fmt.Fprint(w, `{"id":1,"name":"app-1"}`)
+		},
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"oauthapp", "create", "-g", s.URL, "-a", "app-1", "-r", "http://drone.example.com"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(updated.RedirectURIs) != 1 || updated.RedirectURIs[0] != "http://drone.example.com" {
+		t.Errorf("Expecting the existing oAuth application to be updated but got %v", updated)
+	}
+}
+
+func TestCreateOAuthAppRedirectURIs(t *testing.T) {
+	var created oAuth2Option
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/user/applications/oauth2": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Errorf("%v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":1,"name":"cli"}`)
+		},
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"oauthapp", "create", "-g", s.URL, "-a", "cli",
+		"-r", "http://drone-127.0.0.1.sslip.io:30980/login", "-r", "https://drone.example.com/login", "--confidential=false"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := oAuth2Option{
+		Name:         "cli",
+		RedirectURIs: []string{"http://drone-127.0.0.1.sslip.io:30980/login", "https://drone.example.com/login"},
+	}
+	if !reflect.DeepEqual(expected, created) {
+		t.Errorf("Expecting the oAuth application %v to be created but got %v", expected, created)
+	}
+}
@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"fmt"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+)
+
+//Participant is a workshop participant and the credentials of the resources provisioned for them
+type Participant struct {
+	Index         int      `json:"index" yaml:"index"`
+	UserName      string   `json:"userName" yaml:"userName"`
+	FullName      string   `json:"fullName,omitempty" yaml:"fullName,omitempty"`
+	Email         string   `json:"email" yaml:"email"`
+	Password      string   `json:"password" yaml:"password"`
+	OAuthAppName  string   `json:"oAuthAppName,omitempty" yaml:"oAuthAppName,omitempty"`
+	SecretName    string   `json:"secretName,omitempty" yaml:"secretName,omitempty"`
+	RepoCloneURLs []string `json:"repoCloneURLs,omitempty" yaml:"repoCloneURLs,omitempty"`
+	//AccessTokens are the Gitea access tokens of the participant keyed by the token name
+	AccessTokens map[string]string `json:"accessTokens,omitempty" yaml:"accessTokens,omitempty"`
+	//Namespace is the Kubernetes namespace of the participant, empty when the workshop has no participant namespaces
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	//ServiceAccount is the Kubernetes service account of the participant in their namespace
+	ServiceAccount string `json:"serviceAccount,omitempty" yaml:"serviceAccount,omitempty"`
+	//Kubeconfig is the kubeconfig of the participant service account
+	Kubeconfig string `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+}
+
+//participant returns the i-th participant with the default credentials,
+//the name and email are taken from the roster when the participant has an entry in it
+func (u GiteaUser) participant(i int) *Participant {
+	p := &Participant{
+		Index:    i,
+		UserName: participantUserName(i),
+		Email:    fmt.Sprintf("user-%02d@example.com", i),
+		Password: fmt.Sprintf("user-%02d@123", i),
+	}
+	if r := i - u.From; r >= 0 && r < len(u.Roster) {
+		p.FullName = u.Roster[r].Name
+		if u.Roster[r].Email != "" {
+			p.Email = u.Roster[r].Email
+		}
+	}
+	//the namespace templates are validated when the workshop configuration is loaded
+	if ns := u.ParticipantNamespaces; ns != nil {
+		if namespace, serviceAccount, err := ns.participantNamespace(p); err == nil {
+			p.Namespace, p.ServiceAccount = namespace, serviceAccount
+		}
+	}
+	//the first oAuth application is the one the participant signs in to Drone with,
+	//its templates are validated when the workshop configuration is loaded
+	if apps := u.oAuthAppList(); len(apps) > 0 {
+		if o, err := apps[0].options(u.oAuthAppOwner(p)); err == nil {
+			p.OAuthAppName = o.oAuthAppName
+			if u.AddKubernetesSecret {
+				p.SecretName = o.secretName
+			}
+		}
+	}
+	return p
+}
+
+//participantCredentials returns the credentials of the already provisioned i-th participant, the email is
+//the stored one, else the one of the Gitea user e.g. the one the attendee signed up with, else the roster one
+func (opts *WorkshopOptions) participantCredentials(c *gitea.Client, i int) (*Participant, error) {
+	p := opts.GiteaUsers.participant(i)
+	rosterEmail := p.Email
+	p.Email = ""
+	if err := opts.storedCredentials(p); err != nil {
+		return nil, err
+	}
+	if p.Email == "" {
+		u, err := giteaUser(c, p.UserName)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			p.Email = u.Email
+			if p.FullName == "" {
+				p.FullName = u.FullName
+			}
+		}
+	}
+	if p.Email == "" {
+		p.Email = rosterEmail
+	}
+	for _, repoURL := range opts.GiteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return nil, err
+		}
+		repo, resp, err := c.GetRepo(p.UserName, repoName)
+		if err != nil {
+			if isNotFound(resp) {
+				continue
+			}
+			return nil, err
+		}
+		p.RepoCloneURLs = append(p.RepoCloneURLs, repo.CloneURL)
+	}
+	return p, nil
+}
+
+//participantOAuthApps returns the options to manage the oAuth applications of the participant,
+//the options of the shared oAuth applications are returned when the participant is nil
+func (opts *WorkshopOptions) participantOAuthApps(p *Participant, kubeconfig string) ([]*OAuthAppOptions, error) {
+	giteaUsers := opts.GiteaUsers
+	owner := giteaUsers.oAuthAppOwner(p)
+	var oAuthApps []*OAuthAppOptions
+	for _, a := range giteaUsers.oAuthAppList() {
+		oauthOpts, err := a.options(owner)
+		if err != nil {
+			return nil, err
+		}
+		oauthOpts.giteaURL = opts.GiteaURL
+		oauthOpts.giteaAdminUser = opts.GiteaAdminUser
+		oauthOpts.giteaAdminPassword = opts.GiteaAdminPassword
+		if owner != nil {
+			oauthOpts.sudo = owner.UserName
+		}
+		oauthOpts.addKubernetesSecret = giteaUsers.AddKubernetesSecret
+		oauthOpts.namespace = giteaUsers.SecretNamespace
+		if owner != nil && owner.Namespace != "" {
+			oauthOpts.namespace = owner.Namespace
+		}
+		oauthOpts.kubeconfig = kubeconfig
+		oAuthApps = append(oAuthApps, oauthOpts)
+	}
+	return oAuthApps, nil
+}
+
+//provisionParticipant creates the Gitea user, the oAuth applications, the repos and the credential secrets of the participant
+func (opts *WorkshopOptions) provisionParticipant(c *gitea.Client, p *Participant, kubeconfig string) error {
+	giteaUsers := opts.GiteaUsers
+	cp := false
+
+	uOpt := gitea.CreateUserOption{
+		Username:           p.UserName,
+		FullName:           p.FullName,
+		Email:              p.Email,
+		Password:           p.Password,
+		MustChangePassword: &cp,
+		SendNotify:         false,
+	}
+
+	u, _, err := c.AdminCreateUser(uOpt)
+
+	if err != nil {
+		return err
+	}
+	log.Infof("Created user with username %s", u.UserName)
+
+	//Create oAuth2 App
+	c.SetSudo(u.UserName)
+	//Set it back to admin
+	defer c.SetSudo(opts.GiteaAdminUser)
+
+	//the participant namespace holds the oAuth application secrets of the participant
+	if err := opts.applyParticipantNamespace(p, kubeconfig); err != nil {
+		return err
+	}
+	if p.Kubeconfig, err = opts.participantKubeconfig(p, kubeconfig); err != nil {
+		return err
+	}
+
+	//the shared oAuth applications are created once for all the participants
+	if !giteaUsers.SharedOAuthApps {
+		oAuthApps, err := opts.participantOAuthApps(p, kubeconfig)
+		if err != nil {
+			return err
+		}
+
+		for _, oauthOpts := range oAuthApps {
+			if _, err := oauthOpts.createOAuthApp(c); err != nil {
+				return err
+			}
+		}
+
+		//the Drone server signs in with the first oAuth application, once its secret exists
+		if len(oAuthApps) > 0 {
+			if err := opts.applyDroneServer(p, oAuthApps[0], kubeconfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, repoURL := range giteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return err
+		}
+		repo, err := createRepo(c, repoURL, u.UserName, repoName)
+		if err != nil {
+			return err
+		}
+		p.RepoCloneURLs = append(p.RepoCloneURLs, repo.CloneURL)
+	}
+
+	return opts.applyCredentialSecrets(p, kubeconfig)
+}
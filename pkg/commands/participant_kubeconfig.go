@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+//defaultKubeconfigExpiration is how long the participant kubeconfig tokens are valid by default, a day of workshop
+const defaultKubeconfigExpiration = 24 * time.Hour
+
+//ParticipantKubeconfig is the configuration of the kubeconfig generated for the service account of each participant
+type ParticipantKubeconfig struct {
+	//Server is the URL of the Kubernetes API server the participants reach, defaults to the server of the current config,
+	//set it when the workshop is set up from within the cluster
+	Server string `yaml:"server,omitempty"`
+	//Expiration is how long the service account token is valid e.g. 8h, defaults to 24h
+	Expiration string `yaml:"expiration,omitempty"`
+}
+
+//expiration returns how long the service account token is valid
+func (k ParticipantKubeconfig) expiration() (time.Duration, error) {
+	if k.Expiration == "" {
+		return defaultKubeconfigExpiration, nil
+	}
+	d, err := time.ParseDuration(k.Expiration)
+	if err != nil {
+		return 0, fmt.Errorf("invalid kubeconfig expiration %q, %v", k.Expiration, err)
+	}
+	//the API server refuses token requests shorter than 10 minutes
+	if d < 10*time.Minute {
+		return 0, fmt.Errorf("invalid kubeconfig expiration %q, must be at least 10m", k.Expiration)
+	}
+	return d, nil
+}
+
+//participantKubeconfig returns the kubeconfig of the participant service account scoped to the participant namespace,
+//empty when the workshop generates no participant kubeconfigs
+func (opts *WorkshopOptions) participantKubeconfig(p *Participant, kubeconfig string) (string, error) {
+	ns := opts.GiteaUsers.ParticipantNamespaces
+	if ns == nil || ns.Kubeconfig == nil {
+		return "", nil
+	}
+
+	expiration, err := ns.Kubeconfig.expiration()
+	if err != nil {
+		return "", err
+	}
+
+	config, err := newKubernetesConfig(kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", err
+	}
+
+	expirationSeconds := int64(expiration.Seconds())
+	tr, err := clientset.CoreV1().ServiceAccounts(p.Namespace).CreateToken(context.TODO(), p.ServiceAccount, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSeconds},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	caData, err := clusterCA(config)
+	if err != nil {
+		return "", err
+	}
+	server := config.Host
+	if ns.Kubeconfig.Server != "" {
+		server = ns.Kubeconfig.Server
+	}
+
+	b, err := buildKubeconfig(p, server, caData, tr.Status.Token)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("Generated the kubeconfig of %s valid until %s", p.UserName, tr.Status.ExpirationTimestamp)
+
+	return string(b), nil
+}
+
+//clusterCA returns the CA certificate of the cluster of the config
+func clusterCA(config *rest.Config) ([]byte, error) {
+	if len(config.CAData) > 0 || config.CAFile == "" {
+		return config.CAData, nil
+	}
+	return ioutil.ReadFile(config.CAFile)
+}
+
+//buildKubeconfig returns the kubeconfig that authenticates with the service account token of the participant,
+//its context defaults to the participant namespace
+func buildKubeconfig(p *Participant, server string, caData []byte, token string) ([]byte, error) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["workshop"] = &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: caData,
+	}
+	config.AuthInfos[p.UserName] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[p.UserName] = &clientcmdapi.Context{
+		Cluster:   "workshop",
+		AuthInfo:  p.UserName,
+		Namespace: p.Namespace,
+	}
+	config.CurrentContext = p.UserName
+	return clientcmd.Write(*config)
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestBuildKubeconfig(t *testing.T) {
+	p := &Participant{UserName: "user-01", Namespace: "user-01", ServiceAccount: "user-01"}
+	b, err := buildKubeconfig(p, "https://127.0.0.1:6443", []byte("ca-cert"), "sa-token")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	config, err := clientcmd.Load(b)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ctx := config.Contexts[config.CurrentContext]
+	if config.CurrentContext != "user-01" || ctx.Namespace != "user-01" {
+		t.Errorf("Expecting the current context to be user-01 in the namespace user-01 but got %s %v", config.CurrentContext, ctx)
+	}
+	cluster := config.Clusters[ctx.Cluster]
+	if cluster.Server != "https://127.0.0.1:6443" || string(cluster.CertificateAuthorityData) != "ca-cert" {
+		t.Errorf("Expecting the cluster https://127.0.0.1:6443 with its CA but got %v", cluster)
+	}
+	if token := config.AuthInfos[ctx.AuthInfo].Token; token != "sa-token" {
+		t.Errorf("Expecting the service account token to authenticate but got %s", token)
+	}
+}
+
+func TestKubeconfigExpiration(t *testing.T) {
+	if d, err := (ParticipantKubeconfig{}).expiration(); err != nil || d != 24*time.Hour {
+		t.Errorf("Expecting the default expiration of 24h but got %s %v", d, err)
+	}
+	if d, err := (ParticipantKubeconfig{Expiration: "8h"}).expiration(); err != nil || d != 8*time.Hour {
+		t.Errorf("Expecting the expiration of 8h but got %s %v", d, err)
+	}
+	for _, expiration := range []string{"a day", "5m"} {
+		if _, err := (ParticipantKubeconfig{Expiration: expiration}).expiration(); err == nil {
+			t.Errorf("Expecting the expiration %q to be invalid", expiration)
+		}
+	}
+}
+
+func TestHandoutKubeconfig(t *testing.T) {
+	workshopOpts := &WorkshopOptions{GiteaURL: "http://gitea-127.0.0.1.sslip.io:30950/"}
+	p := &Participant{UserName: "user-01", Password: "user-01@123", Kubeconfig: "apiVersion: v1\nkind: Config\n"}
+
+	outputDir := t.TempDir()
+	opts := &HandoutsOptions{outputDir: outputDir, format: "markdown"}
+	if err := opts.writeHandout(workshopOpts, p); err != nil {
+		t.Fatalf("%v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(outputDir, "user-01.md"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.Contains(string(b), "## Kubeconfig\n\n```yaml\napiVersion: v1\nkind: Config\n```") {
+		t.Errorf("Expecting the Markdown handout to contain the kubeconfig but got\n%s", b)
+	}
+}
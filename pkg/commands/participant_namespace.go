@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+//participantLabel is the label of the namespaces created for the participants, its value is the participant username,
+//only the namespaces with the label of the participant are used and deleted for them
+const participantLabel = "drone-workshop/participant"
+
+//ParticipantNamespaces is the configuration of the Kubernetes namespace created for each participant,
+//the participant service account gets edit rights on it and the oAuth secrets are created in it
+type ParticipantNamespaces struct {
+	//Name is the Go template of the namespace name, defaults to {{ .UserName }}
+	Name string `yaml:"name,omitempty"`
+	//ServiceAccount is the Go template of the participant service account name, defaults to {{ .UserName }}
+	ServiceAccount string `yaml:"serviceAccount,omitempty"`
+	//ResourceQuota are the hard limits of the namespace e.g. requests.cpu: "2", none when empty
+	ResourceQuota map[string]string `yaml:"resourceQuota,omitempty"`
+	//LimitRange are the default resources of the namespace containers, none when empty
+	LimitRange *ContainerLimits `yaml:"limitRange,omitempty"`
+	//Kubeconfig generates a kubeconfig of the participant service account when set
+	Kubeconfig *ParticipantKubeconfig `yaml:"kubeconfig,omitempty"`
+}
+
+//ContainerLimits are the default resource requests and limits of the containers e.g. cpu: 500m
+type ContainerLimits struct {
+	DefaultRequest map[string]string `yaml:"defaultRequest,omitempty"`
+	Default        map[string]string `yaml:"default,omitempty"`
+}
+
+//participantNamespace returns the namespace and service account names of the participant
+func (ns ParticipantNamespaces) participantNamespace(p *Participant) (string, string, error) {
+	namespace, serviceAccount := p.UserName, p.UserName
+	var err error
+	if ns.Name != "" {
+		if namespace, err = renderTemplate(ns.Name, p); err != nil {
+			return "", "", err
+		}
+	}
+	if ns.ServiceAccount != "" {
+		if serviceAccount, err = renderTemplate(ns.ServiceAccount, p); err != nil {
+			return "", "", err
+		}
+	}
+	return namespace, serviceAccount, nil
+}
+
+//validateParticipantNamespaces checks the templates render for a participant and the resource quantities parse
+func (u GiteaUser) validateParticipantNamespaces() error {
+	ns := u.ParticipantNamespaces
+	if ns == nil {
+		return nil
+	}
+	if u.SharedOAuthApps && u.AddKubernetesSecret {
+		log.Warnf("The shared oAuth application secrets are created in %q and not in the participant namespaces", u.SecretNamespace)
+	}
+	p := u.participant(u.From)
+	if _, _, err := ns.participantNamespace(p); err != nil {
+		return fmt.Errorf("invalid participant namespaces, %v", err)
+	}
+	if _, err := participantResourceQuota(p, ns.ResourceQuota); err != nil {
+		return fmt.Errorf("invalid participant namespace resource quota, %v", err)
+	}
+	if ns.LimitRange != nil {
+		if _, err := participantLimitRange(p, ns.LimitRange); err != nil {
+			return fmt.Errorf("invalid participant namespace limit range, %v", err)
+		}
+	}
+	if ns.Kubeconfig != nil {
+		if _, err := ns.Kubeconfig.expiration(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//applyParticipantNamespace creates or updates the namespace of the participant with
+//the service account, its edit role binding, the resource quota and the limit range
+func (opts *WorkshopOptions) applyParticipantNamespace(p *Participant, kubeconfig string) error {
+	ns := opts.GiteaUsers.ParticipantNamespaces
+	if ns == nil {
+		return nil
+	}
+
+	clientset, err := newKubernetesClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	namespaces := clientset.CoreV1().Namespaces()
+	existing, err := namespaces.Get(context.TODO(), p.Namespace, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		_, err = namespaces.Create(context.TODO(), &apiv1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   p.Namespace,
+				Labels: map[string]string{participantLabel: p.UserName},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes namespace %s", p.Namespace)
+	} else if !isParticipantNamespace(existing, p) {
+		//e.g. default or kube-system, the teardown would delete it
+		return fmt.Errorf("the namespace %s exists and is not labelled %s=%s, refusing to use it for the participant", p.Namespace, participantLabel, p.UserName)
+	}
+
+	if err := applyServiceAccount(clientset, p.Namespace, p.ServiceAccount, ""); err != nil {
+		return err
+	}
+	if err := applyRoleBinding(clientset, participantRoleBinding(p)); err != nil {
+		return err
+	}
+
+	if len(ns.ResourceQuota) > 0 {
+		quota, err := participantResourceQuota(p, ns.ResourceQuota)
+		if err != nil {
+			return err
+		}
+		if err := applyResourceQuota(clientset, quota); err != nil {
+			return err
+		}
+	}
+
+	if ns.LimitRange != nil {
+		limitRange, err := participantLimitRange(p, ns.LimitRange)
+		if err != nil {
+			return err
+		}
+		if err := applyLimitRange(clientset, limitRange); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//isParticipantNamespace is true when the namespace was created for the participant
+func isParticipantNamespace(ns *apiv1.Namespace, p *Participant) bool {
+	return ns.Labels[participantLabel] == p.UserName
+}
+
+//participantRoleBinding returns the role binding granting the participant service account edit rights on their namespace
+func participantRoleBinding(p *Participant) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-edit", p.ServiceAccount),
+			Namespace: p.Namespace,
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      p.ServiceAccount,
+			Namespace: p.Namespace,
+		}},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "edit",
+		},
+	}
+}
+
+//participantResourceQuota returns the resource quota of the participant namespace with the hard limits
+func participantResourceQuota(p *Participant, hard map[string]string) (*apiv1.ResourceQuota, error) {
+	hardList, err := resourceList(hard)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "workshop-quota",
+			Namespace: p.Namespace,
+		},
+		Spec: apiv1.ResourceQuotaSpec{Hard: hardList},
+	}, nil
+}
+
+//participantLimitRange returns the limit range of the participant namespace with the container defaults
+func participantLimitRange(p *Participant, limits *ContainerLimits) (*apiv1.LimitRange, error) {
+	defaultRequest, err := resourceList(limits.DefaultRequest)
+	if err != nil {
+		return nil, err
+	}
+	defaultLimit, err := resourceList(limits.Default)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "workshop-limits",
+			Namespace: p.Namespace,
+		},
+		Spec: apiv1.LimitRangeSpec{
+			Limits: []apiv1.LimitRangeItem{{
+				Type:           apiv1.LimitTypeContainer,
+				DefaultRequest: defaultRequest,
+				Default:        defaultLimit,
+			}},
+		},
+	}, nil
+}
+
+//resourceList parses the resource quantities e.g. cpu: 500m
+func resourceList(quantities map[string]string) (apiv1.ResourceList, error) {
+	if len(quantities) == 0 {
+		return nil, nil
+	}
+	list := make(apiv1.ResourceList, len(quantities))
+	for name, q := range quantities {
+		quantity, err := resource.ParseQuantity(q)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %q of %s, %v", q, name, err)
+		}
+		list[apiv1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+//applyRoleBinding creates the role binding or updates the subjects of the existing one
+func applyRoleBinding(clientset kubernetes.Interface, rb *rbacv1.RoleBinding) error {
+	roleBindings := clientset.RbacV1().RoleBindings(rb.Namespace)
+	existing, err := roleBindings.Get(context.TODO(), rb.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if _, err := roleBindings.Create(context.TODO(), rb, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes role binding %s/%s", rb.Namespace, rb.Name)
+		return nil
+	}
+	//the role of a binding can't be changed
+	existing.Subjects = rb.Subjects
+	_, err = roleBindings.Update(context.TODO(), existing, metav1.UpdateOptions{})
+	return err
+}
+
+//applyResourceQuota creates the resource quota or updates the spec of the existing one
+func applyResourceQuota(clientset kubernetes.Interface, quota *apiv1.ResourceQuota) error {
+	quotas := clientset.CoreV1().ResourceQuotas(quota.Namespace)
+	existing, err := quotas.Get(context.TODO(), quota.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if _, err := quotas.Create(context.TODO(), quota, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes resource quota %s/%s", quota.Namespace, quota.Name)
+		return nil
+	}
+	existing.Spec = quota.Spec
+	_, err = quotas.Update(context.TODO(), existing, metav1.UpdateOptions{})
+	return err
+}
+
+//applyLimitRange creates the limit range or updates the spec of the existing one
+func applyLimitRange(clientset kubernetes.Interface, limitRange *apiv1.LimitRange) error {
+	limitRanges := clientset.CoreV1().LimitRanges(limitRange.Namespace)
+	existing, err := limitRanges.Get(context.TODO(), limitRange.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if _, err := limitRanges.Create(context.TODO(), limitRange, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("Created Kubernetes limit range %s/%s", limitRange.Namespace, limitRange.Name)
+		return nil
+	}
+	existing.Spec = limitRange.Spec
+	_, err = limitRanges.Update(context.TODO(), existing, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParticipantNamespace(t *testing.T) {
+	giteaUsers := GiteaUser{
+		From:                  1,
+		To:                    2,
+		OAuthAppName:          "drone",
+		OAuthRedirectURI:      "http://drone-127.0.0.1.sslip.io:30980",
+		SecretNamespace:       "drone",
+		AddKubernetesSecret:   true,
+		ParticipantNamespaces: &ParticipantNamespaces{Name: "workshop-{{ .UserName }}"},
+	}
+	p := giteaUsers.participant(2)
+	if p.Namespace != "workshop-user-02" || p.ServiceAccount != "user-02" {
+		t.Errorf("Expecting the participant namespace workshop-user-02 and service account user-02 but got %s %s", p.Namespace, p.ServiceAccount)
+	}
+
+	opts := &WorkshopOptions{GiteaUsers: giteaUsers}
+	oAuthApps, err := opts.participantOAuthApps(p, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if oAuthApps[0].namespace != "workshop-user-02" {
+		t.Errorf("Expecting the oAuth application secret to be created in the participant namespace but got %s", oAuthApps[0].namespace)
+	}
+
+	rb := participantRoleBinding(p)
+	if rb.Name != "user-02-edit" || rb.Namespace != "workshop-user-02" || rb.RoleRef.Name != "edit" ||
+		rb.Subjects[0].Name != "user-02" || rb.Subjects[0].Namespace != "workshop-user-02" {
+		t.Errorf("Expecting the service account user-02 to be bound to the edit role but got %v", rb)
+	}
+}
+
+func TestParticipantNamespaceResources(t *testing.T) {
+	p := &Participant{UserName: "user-01", Namespace: "user-01"}
+	quota, err := participantResourceQuota(p, map[string]string{"requests.cpu": "2", "pods": "10"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if cpu := quota.Spec.Hard[apiv1.ResourceRequestsCPU]; cpu.String() != "2" || quota.Namespace != "user-01" {
+		t.Errorf("Expecting the quota of 2 cpus in user-01 but got %s in %s", cpu.String(), quota.Namespace)
+	}
+
+	limitRange, err := participantLimitRange(p, &ContainerLimits{
+		DefaultRequest: map[string]string{"cpu": "100m"},
+		Default:        map[string]string{"memory": "512Mi"},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	limits := limitRange.Spec.Limits[0]
+	if cpu, memory := limits.DefaultRequest[apiv1.ResourceCPU], limits.Default[apiv1.ResourceMemory]; cpu.String() != "100m" || memory.String() != "512Mi" {
+		t.Errorf("Expecting the container defaults 100m cpu and 512Mi memory but got %s %s", cpu.String(), memory.String())
+	}
+
+	if _, err := participantResourceQuota(p, map[string]string{"requests.cpu": "two"}); err == nil {
+		t.Errorf("Expecting an invalid quantity to fail")
+	}
+}
+
+func TestValidateParticipantNamespaces(t *testing.T) {
+	giteaUsers := GiteaUser{From: 1, To: 2, ParticipantNamespaces: &ParticipantNamespaces{Name: "{{ .Team }}"}}
+	if err := giteaUsers.validateParticipantNamespaces(); err == nil {
+		t.Errorf("Expecting a namespace template referring to an unknown field to fail")
+	}
+	giteaUsers.ParticipantNamespaces = &ParticipantNamespaces{LimitRange: &ContainerLimits{Default: map[string]string{"memory": "lots"}}}
+	if err := giteaUsers.validateParticipantNamespaces(); err == nil {
+		t.Errorf("Expecting an invalid limit range to fail")
+	}
+}
+
+func TestApplyParticipantNamespaceUnlabelled(t *testing.T) {
+	clientset := useFakeKubernetes(t, &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	opts := &WorkshopOptions{GiteaUsers: GiteaUser{
+		From:                  1,
+		To:                    2,
+		ParticipantNamespaces: &ParticipantNamespaces{Name: "default"},
+	}}
+	p := opts.GiteaUsers.participant(1)
+	if err := opts.applyParticipantNamespace(p, ""); err == nil || !strings.Contains(err.Error(), participantLabel) {
+		t.Errorf("Expecting the existing namespace without the participant label not to be used but got %v", err)
+	}
+
+	if err := deleteParticipantNamespace(clientset, p); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := clientset.CoreV1().Namespaces().Get(context.TODO(), "default", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expecting the namespace without the participant label to be kept but got %v", err)
+	}
+
+	p = opts.GiteaUsers.participant(2)
+	p.Namespace = "user-02"
+	if err := opts.applyParticipantNamespace(p, ""); err != nil {
+		t.Fatalf("%v", err)
+	}
+	//applied again to the namespace created for the participant
+	if err := opts.applyParticipantNamespace(p, ""); err != nil {
+		t.Errorf("Expecting the namespace created for the participant to be used but got %v", err)
+	}
+	if err := deleteParticipantNamespace(clientset, p); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := clientset.CoreV1().Namespaces().Get(context.TODO(), "user-02", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Expecting the namespace of the participant to be deleted but got %v", err)
+	}
+}
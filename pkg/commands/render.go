@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+//NewRenderCommand instantiates the new instance of the RenderCommand that groups
+//the commands that render configuration files from the workshop configuration
+func NewRenderCommand() *cobra.Command {
+	renderCmd := &cobra.Command{
+		Use:   "render",
+		Short: "Render configuration files from the workshop configuration",
+	}
+
+	renderCmd.AddCommand(NewDroneValuesCommand())
+
+	return renderCmd
+}
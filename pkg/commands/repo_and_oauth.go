@@ -3,11 +3,13 @@ package commands
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"code.gitea.io/sdk/gitea"
 	log "github.com/sirupsen/logrus"
 	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -15,28 +17,16 @@ import (
 )
 
 func (opts *OAuthAppOptions) createOAuthApp(c *gitea.Client) (*gitea.Oauth2, error) {
-	var oAuthApp *gitea.Oauth2
-
-	oAuthApps, _, err := c.ListOauth2(gitea.ListOauth2Option{})
+	oAuthApp, err := findOAuthApp(c, opts.oAuthAppName)
 
 	if err != nil {
 		return nil, err
 	}
 
-	var appExists = false
-	for _, o := range oAuthApps {
-		if o.Name == opts.oAuthAppName {
-			appExists = true
-			break
-		}
-	}
-
-	if !appExists {
+	if oAuthApp == nil {
 		log.Debugln("Creating new oAuth App")
 
-		oAuthApp, _, err := c.CreateOauth2(gitea.CreateOauth2Option{
-			RedirectURIs: []string{opts.appRedirectURL},
-			Name:         opts.oAuthAppName})
+		oAuthApp, err = opts.saveOAuth2(0)
 		if err != nil {
 			return nil, err
 		}
@@ -52,78 +42,255 @@ func (opts *OAuthAppOptions) createOAuthApp(c *gitea.Client) (*gitea.Oauth2, err
 		log.Debugf("\noAuth application %s ClientID:%s ClientSecret:%s\n", opts.oAuthAppName, oAuthApp.ClientID, oAuthApp.ClientSecret)
 	} else {
 		log.Infof("\noAuth app %s already exists, updating", opts.oAuthAppName)
-		oAuthApp, _, err = c.UpdateOauth2(oAuthApp.ID,
-			gitea.CreateOauth2Option{
-				RedirectURIs: []string{opts.appRedirectURL},
-				Name:         opts.oAuthAppName,
-			})
+		oAuthApp, err = opts.saveOAuth2(oAuthApp.ID)
 		if err != nil {
 			return nil, err
 		}
+
+		//Gitea regenerates the client secret on update, keep the secret in sync
+		if opts.addKubernetesSecret {
+			err = opts.updateKubernetesSecret(oAuthApp)
+
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return oAuthApp, nil
 }
 
-// generateKubernetesSecret generates a Kubernetes secret
-// for the oAuth Application and stores the ClientID and ClientSecret in it.
-// The default name of the secret is <oauth-app-name>-secret
-func (opts *OAuthAppOptions) generateKubernetesSecret(o *gitea.Oauth2) error {
-	var config *rest.Config
-	var err error
-	if opts.kubeconfig != "" {
-		config, err = clientcmd.BuildConfigFromFlags("", opts.kubeconfig)
+//oAuth2Option is the option to create or update a Gitea oAuth application, unlike gitea.CreateOauth2Option
+//it has the confidential client setting without which Gitea creates public clients that require PKCE
+type oAuth2Option struct {
+	Name               string   `json:"name"`
+	RedirectURIs       []string `json:"redirect_uris"`
+	ConfidentialClient bool     `json:"confidential_client"`
+}
+
+//saveOAuth2 creates the oAuth application of the sudo user, or updates the one with the id when it is not 0,
+//with the redirect URIs and the confidential client setting of the options
+func (opts *OAuthAppOptions) saveOAuth2(id int64) (*gitea.Oauth2, error) {
+	method, apiPath := http.MethodPost, "/user/applications/oauth2"
+	if id != 0 {
+		method, apiPath = http.MethodPatch, fmt.Sprintf("%s/%d", apiPath, id)
+	}
+
+	o := new(gitea.Oauth2)
+	if err := giteaRequest(opts.giteaURL, opts.giteaAdminUser, opts.giteaAdminPassword, opts.sudo, method, apiPath, oAuth2Option{
+		Name:               opts.oAuthAppName,
+		RedirectURIs:       opts.appRedirectURLs,
+		ConfidentialClient: opts.confidentialClient,
+	}, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+//oAuth2Confidential reports whether the oAuth application of the sudo user with the id is a confidential client,
+//gitea.Oauth2 lacks the setting and the Gitea versions without it only have confidential clients
+func (opts *OAuthAppOptions) oAuth2Confidential(id int64) (bool, error) {
+	var o struct {
+		ConfidentialClient *bool `json:"confidential_client"`
+	}
+	if err := giteaRequest(opts.giteaURL, opts.giteaAdminUser, opts.giteaAdminPassword, opts.sudo, http.MethodGet,
+		fmt.Sprintf("/user/applications/oauth2/%d", id), nil, &o); err != nil {
+		return false, err
+	}
+	return o.ConfidentialClient == nil || *o.ConfidentialClient, nil
+}
+
+//listOAuthApps lists the oAuth applications of the client user across all the pages
+func listOAuthApps(c *gitea.Client) ([]*gitea.Oauth2, error) {
+	var oAuthApps []*gitea.Oauth2
+	opt := gitea.ListOauth2Option{ListOptions: gitea.ListOptions{Page: 1, PageSize: 50}}
+	for {
+		page, _, err := c.ListOauth2(opt)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.Debugln("Using out of Cluster Config")
-	} else {
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			return err
+		oAuthApps = append(oAuthApps, page...)
+		if len(page) < opt.PageSize {
+			break
 		}
-		log.Debugln("Using InCluster Config")
+		opt.Page++
+	}
+	return oAuthApps, nil
+}
+
+//findOAuthApp finds the oAuth application of the client user by its name,
+//nil is returned if there is no such application
+func findOAuthApp(c *gitea.Client, name string) (*gitea.Oauth2, error) {
+	oAuthApps, err := listOAuthApps(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range oAuthApps {
+		if o.Name == name {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+//newKubernetesClient creates the Kubernetes client using the kubeconfig,
+//the in cluster config is used when the kubeconfig is empty, a variable for the tests to use a fake clientset
+var newKubernetesClient = func(kubeconfig string) (kubernetes.Interface, error) {
+	config, err := newKubernetesConfig(kubeconfig)
+	if err != nil {
+		return nil, err
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Debugln("Got Client Set")
 
-	sec, _ := randomHex(16)
+	return clientset, nil
+}
+
+//newKubernetesConfig returns the config of the kubeconfig file, the in cluster config when the kubeconfig is empty
+func newKubernetesConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig != "" {
+		config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugln("Using out of Cluster Config")
+		return config, nil
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	log.Debugln("Using InCluster Config")
+	return config, nil
+}
+
+//kubernetesSecretName returns the name of the Kubernetes secret of the oAuth Application,
+//defaults to <oauth-app-name>-secret
+func (opts *OAuthAppOptions) kubernetesSecretName() string {
+	if opts.secretName != "" {
+		return opts.secretName
+	}
+	return fmt.Sprintf("%s-secret", opts.oAuthAppName)
+}
+
+//kubernetesSecretKeys returns the keys of the Kubernetes secret of the oAuth Application,
+//defaults to the keys of Drone
+func (opts *OAuthAppOptions) kubernetesSecretKeys() SecretKeys {
+	if opts.secretKeys == (SecretKeys{}) {
+		return droneSecretKeys
+	}
+	return opts.secretKeys
+}
+
+// generateKubernetesSecret generates a Kubernetes secret
+// for the oAuth Application and stores the ClientID and ClientSecret in it.
+// The default name of the secret is <oauth-app-name>-secret
+func (opts *OAuthAppOptions) generateKubernetesSecret(o *gitea.Oauth2) error {
+	clientset, err := newKubernetesClient(opts.kubeconfig)
+	if err != nil {
+		return err
+	}
 
 	//use defaults namespace
 	if opts.namespace == "" {
 		opts.namespace = "default"
 	}
 
+	keys := opts.kubernetesSecretKeys()
+	data := map[string]string{
+		keys.ClientID:     o.ClientID,
+		keys.ClientSecret: o.ClientSecret,
+	}
+	if keys.RandomSecret != "" {
+		data[keys.RandomSecret], _ = randomHex(16)
+	}
+
 	_, err = clientset.CoreV1().Secrets(opts.namespace).Create(context.TODO(), &apiv1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: fmt.Sprintf("%s-secret", opts.oAuthAppName),
-		},
-		StringData: map[string]string{
-			"DRONE_GITEA_CLIENT_ID":     o.ClientID,
-			"DRONE_GITEA_CLIENT_SECRET": o.ClientSecret,
-			"DRONE_RPC_SECRET":          sec,
+			Name:   opts.kubernetesSecretName(),
+			Labels: managedLabels(opts.secretLabels),
 		},
+		StringData: data,
 	}, metav1.CreateOptions{})
 
 	if err != nil {
 		return err
 	}
-	log.Infof("Created Kubernetes secret %s", opts.oAuthAppName)
+	log.Infof("Created Kubernetes secret %s", opts.kubernetesSecretName())
 	return nil
 }
 
-func createRepo(c *gitea.Client, githubTemplateRepo, user, repoName string) error {
+// updateKubernetesSecret updates the ClientID and ClientSecret of the oAuth Application
+// in its Kubernetes secret keeping the other keys, the secret is generated if it does not exist
+func (opts *OAuthAppOptions) updateKubernetesSecret(o *gitea.Oauth2) error {
+	clientset, err := newKubernetesClient(opts.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	//use defaults namespace
+	if opts.namespace == "" {
+		opts.namespace = "default"
+	}
+
+	secrets := clientset.CoreV1().Secrets(opts.namespace)
+	secret, err := secrets.Get(context.TODO(), opts.kubernetesSecretName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return opts.generateKubernetesSecret(o)
+		}
+		return err
+	}
+
+	if !isManagedSecret(secret) {
+		return fmt.Errorf("the secret %s/%s exists and is not labelled %s=%s, it is not updated", opts.namespace, secret.Name, managedByLabel, managedBy)
+	}
+
+	if secret.StringData == nil {
+		secret.StringData = make(map[string]string)
+	}
+	for k, v := range opts.secretLabels {
+		secret.Labels[k] = v
+	}
+	keys := opts.kubernetesSecretKeys()
+	secret.StringData[keys.ClientID] = o.ClientID
+	secret.StringData[keys.ClientSecret] = o.ClientSecret
+
+	if _, err = secrets.Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	log.Infof("Updated Kubernetes secret %s", secret.Name)
+	return nil
+}
+
+// deleteKubernetesSecret deletes the Kubernetes secret of the oAuth Application if it exists
+func (opts *OAuthAppOptions) deleteKubernetesSecret() error {
+	clientset, err := newKubernetesClient(opts.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	//use defaults namespace
+	if opts.namespace == "" {
+		opts.namespace = "default"
+	}
+
+	return deleteSecret(clientset, opts.namespace, opts.kubernetesSecretName())
+}
+
+func createRepo(c *gitea.Client, githubTemplateRepo, user, repoName string) (*gitea.Repository, error) {
 	repo, _, err := c.GetRepo(user, repoName)
 
 	if err != nil {
 		//raise panic if error is not repo not found or repo exists
 		if strings.TrimSpace(err.Error()) != "404 Not Found" && strings.TrimSpace(err.Error()) != "409 Conflict" {
-			return err
+			return nil, err
 		}
 	}
 
@@ -135,12 +302,13 @@ func createRepo(c *gitea.Client, githubTemplateRepo, user, repoName string) erro
 		})
 
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.Infof("Repo %s successfully created for user %s, you can clone via %s", newR.Name, user, repo.CloneURL)
-	} else {
-		log.Infof("Repo %s already exists for user %s skipping creation,you can clone via %s", repo.Name, user, repo.CloneURL)
+		log.Infof("Repo %s successfully created for user %s, you can clone via %s", newR.Name, user, newR.CloneURL)
+		return newR, nil
 	}
 
-	return nil
+	log.Infof("Repo %s already exists for user %s skipping creation,you can clone via %s", repo.Name, user, repo.CloneURL)
+
+	return repo, nil
 }
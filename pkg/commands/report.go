@@ -0,0 +1,272 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//ReportOptions the options to report the progress of the workshop participants
+type ReportOptions struct {
+	configFile    string
+	output        string
+	exerciseLabel string
+}
+
+//RepoProgress is the progress of a participant on a workshop repo
+type RepoProgress struct {
+	User             string            `json:"user"`
+	Repo             string            `json:"repo"`
+	Missing          bool              `json:"missing,omitempty"`
+	Commit           string            `json:"commit,omitempty"`
+	CommitMessage    string            `json:"commitMessage,omitempty"`
+	CommitAuthor     string            `json:"commitAuthor,omitempty"`
+	CommitDate       string            `json:"commitDate,omitempty"`
+	Pipeline         gitea.StatusState `json:"pipeline,omitempty"`
+	OpenPullRequests int               `json:"openPullRequests"`
+	ClosedIssues     int               `json:"closedIssues"`
+}
+
+// ReportOptions implements Interface
+var _ Command = (*ReportOptions)(nil)
+
+var reportFormats = []string{"text", "markdown", "csv", "json"}
+
+var reportCommandExample = fmt.Sprintf(`
+  # Print the progress of the participants as a table
+  %[1]s report --workshop-file workshop.yaml
+  # Write the progress as Markdown, counting only the closed issues labelled 'exercise'
+  %[1]s report -f workshop.yaml --output markdown --exercise-label exercise > progress.md
+`, ExamplePrefix())
+
+//NewReportCommand instantiates the new instance of the ReportCommand
+func NewReportCommand() *cobra.Command {
+	reportOpts := &ReportOptions{}
+
+	reportCmd := &cobra.Command{
+		Use:     "report",
+		Short:   "Report the pipeline status and repo activity of the workshop participants",
+		Example: reportCommandExample,
+		RunE:    reportOpts.Execute,
+		PreRunE: reportOpts.Validate,
+	}
+
+	reportOpts.AddFlags(reportCmd)
+
+	return reportCmd
+}
+
+// AddFlags implements Command
+func (opts *ReportOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", fmt.Sprintf("The report format, one of %s", strings.Join(reportFormats, ", ")))
+	cmd.Flags().StringVarP(&opts.exerciseLabel, "exercise-label", "l", "", "Count only the closed issues with this label")
+}
+
+// Validate implements Command
+func (opts *ReportOptions) Validate(cmd *cobra.Command, args []string) error {
+	for _, f := range reportFormats {
+		if opts.output == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported report format %q, must be one of %s", opts.output, strings.Join(reportFormats, ", "))
+}
+
+// Execute implements Command
+func (opts *ReportOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	progress, err := workshopOpts.collectProgress(c, opts.exerciseLabel)
+	if err != nil {
+		return err
+	}
+
+	return writeReport(cmd.OutOrStdout(), opts.output, progress)
+}
+
+//collectProgress gathers the progress of every participant on every workshop repo
+func (opts *WorkshopOptions) collectProgress(c *gitea.Client, exerciseLabel string) ([]RepoProgress, error) {
+	var progress []RepoProgress
+	giteaUsers := opts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		userName := participantUserName(i)
+		for _, repoURL := range giteaUsers.Repos {
+			repoName, err := repoNameFromURL(repoURL)
+			if err != nil {
+				return nil, err
+			}
+			p, err := repoProgress(c, userName, repoName, exerciseLabel)
+			if err != nil {
+				return nil, err
+			}
+			progress = append(progress, *p)
+		}
+	}
+	return progress, nil
+}
+
+//repoProgress gathers the latest commit, its combined status, the open pull requests and
+//the closed issues of the user's repo
+func repoProgress(c *gitea.Client, user, repoName, exerciseLabel string) (*RepoProgress, error) {
+	p := &RepoProgress{
+		User: user,
+		Repo: repoName,
+	}
+
+	repo, resp, err := c.GetRepo(user, repoName)
+	if err != nil {
+		if isNotFound(resp) {
+			p.Missing = true
+			return p, nil
+		}
+		return nil, err
+	}
+
+	if !repo.Empty {
+		commits, _, err := c.ListRepoCommits(user, repoName, gitea.ListCommitOptions{
+			ListOptions: gitea.ListOptions{Page: 1, PageSize: 1},
+			SHA:         repo.DefaultBranch,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(commits) > 0 {
+			commit := commits[0]
+			p.Commit = commit.SHA
+			if rc := commit.RepoCommit; rc != nil {
+				p.CommitMessage = strings.SplitN(strings.TrimSpace(rc.Message), "\n", 2)[0]
+				if rc.Author != nil {
+					p.CommitAuthor = rc.Author.Name
+					p.CommitDate = rc.Author.Date
+				}
+			}
+
+			status, _, err := c.GetCombinedStatus(user, repoName, commit.SHA)
+			if err != nil {
+				return nil, err
+			}
+			if status.TotalCount > 0 {
+				p.Pipeline = status.State
+			}
+		}
+	}
+
+	for page := 1; ; page++ {
+		pulls, _, err := c.ListRepoPullRequests(user, repoName, gitea.ListPullRequestsOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: 50},
+			State:       gitea.StateOpen,
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.OpenPullRequests += len(pulls)
+		if len(pulls) < 50 {
+			break
+		}
+	}
+
+	issueOpts := gitea.ListIssueOption{
+		State: gitea.StateClosed,
+		Type:  gitea.IssueTypeIssue,
+	}
+	if exerciseLabel != "" {
+		issueOpts.Labels = []string{exerciseLabel}
+	}
+	for page := 1; ; page++ {
+		issueOpts.ListOptions = gitea.ListOptions{Page: page, PageSize: 50}
+		issues, _, err := c.ListRepoIssues(user, repoName, issueOpts)
+		if err != nil {
+			return nil, err
+		}
+		p.ClosedIssues += len(issues)
+		if len(issues) < 50 {
+			break
+		}
+	}
+
+	return p, nil
+}
+
+var reportHeader = []string{"USER", "REPO", "COMMIT", "MESSAGE", "AUTHOR", "DATE", "PIPELINE", "OPEN PRS", "CLOSED ISSUES"}
+
+//row returns the report columns of the progress
+func (p RepoProgress) row() []string {
+	if p.Missing {
+		return []string{p.User, p.Repo, "missing", "", "", "", "", "", ""}
+	}
+	pipeline := string(p.Pipeline)
+	if pipeline == "" {
+		pipeline = "none"
+	}
+	return []string{
+		p.User,
+		p.Repo,
+		fmt.Sprintf("%.7s", p.Commit),
+		p.CommitMessage,
+		p.CommitAuthor,
+		p.CommitDate,
+		pipeline,
+		strconv.Itoa(p.OpenPullRequests),
+		strconv.Itoa(p.ClosedIssues),
+	}
+}
+
+//writeReport writes the progress to out in the given format
+func writeReport(out io.Writer, format string, progress []RepoProgress) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(progress)
+	case "csv":
+		w := csv.NewWriter(out)
+		if err := w.Write(reportHeader); err != nil {
+			return err
+		}
+		for _, p := range progress {
+			if err := w.Write(p.row()); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	case "markdown":
+		fmt.Fprintf(out, "| %s |\n", strings.Join(reportHeader, " | "))
+		fmt.Fprintf(out, "|%s\n", strings.Repeat(" --- |", len(reportHeader)))
+		for _, p := range progress {
+			cols := p.row()
+			for i, col := range cols {
+				cols[i] = strings.ReplaceAll(col, "|", `\|`)
+			}
+			fmt.Fprintf(out, "| %s |\n", strings.Join(cols, " | "))
+		}
+		return nil
+	default:
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(reportHeader, "\t"))
+		for _, p := range progress {
+			fmt.Fprintln(w, strings.Join(p.row(), "\t"))
+		}
+		return w.Flush()
+	}
+}
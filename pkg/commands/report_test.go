@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+func TestReport(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/repos/user-01/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","default_branch":"main"}`)
+		},
+		"/api/v1/repos/user-01/jar-stack/commits": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"sha":"c2c2c2c2c2","commit":{"message":"Fix the pipeline\n\nUse the right image","author":{"name":"User 01","date":"2022-08-02T10:00:00Z"}}}]`)
+		},
+		"/api/v1/repos/user-01/jar-stack/commits/c2c2c2c2c2/status": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"state":"success","total_count":1}`)
+		},
+		"/api/v1/repos/user-01/jar-stack/pulls": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"number":1}]`)
+		},
+		"/api/v1/repos/user-01/jar-stack/issues": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("labels") != "exercise" || r.URL.Query().Get("state") != "closed" {
+				t.Errorf("Expecting closed issues labelled exercise to be queried but got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `[{"number":2},{"number":3}]`)
+		},
+		"/api/v1/repos/user-02/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+	})
+
+	var out bytes.Buffer
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"report", "-f", writeWorkshopFile(t, s.URL), "-o", "json", "-l", "exercise"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var actual []RepoProgress
+	if err := json.Unmarshal(out.Bytes(), &actual); err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := []RepoProgress{
+		{
+			User:             "user-01",
+			Repo:             "jar-stack",
+			Commit:           "c2c2c2c2c2",
+			CommitMessage:    "Fix the pipeline",
+			CommitAuthor:     "User 01",
+			CommitDate:       "2022-08-02T10:00:00Z",
+			Pipeline:         gitea.StatusSuccess,
+			OpenPullRequests: 1,
+			ClosedIssues:     2,
+		},
+		{User: "user-02", Repo: "jar-stack", Missing: true},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expecting progress %v but got %v", expected, actual)
+	}
+}
+
+func TestWriteReportMarkdown(t *testing.T) {
+	var out bytes.Buffer
+	err := writeReport(&out, "markdown", []RepoProgress{
+		{User: "user-01", Repo: "jar-stack", Commit: "c2c2c2c2c2", CommitMessage: "Fix | pipe", ClosedIssues: 1},
+		{User: "user-02", Repo: "jar-stack", Missing: true},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := `| USER | REPO | COMMIT | MESSAGE | AUTHOR | DATE | PIPELINE | OPEN PRS | CLOSED ISSUES |
+| --- | --- | --- | --- | --- | --- | --- | --- | --- |
+| user-01 | jar-stack | c2c2c2c | Fix \| pipe |  |  | none | 0 | 1 |
+| user-02 | jar-stack | missing |  |  |  |  |  |  |
+`
+	if out.String() != expected {
+		t.Errorf("Expecting report\n%s\nbut got\n%s", expected, out.String())
+	}
+}
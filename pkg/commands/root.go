@@ -26,6 +26,21 @@ func NewRootCommand() *cobra.Command {
 
 	rootCmd.AddCommand(NewVersionCommand())
 	rootCmd.AddCommand(NewWorkshopSetupCommand())
+	rootCmd.AddCommand(NewSyncTemplateCommand())
+	rootCmd.AddCommand(NewArchiveWorkshopCommand())
+	rootCmd.AddCommand(NewReportCommand())
+	rootCmd.AddCommand(NewServeCommand())
+	rootCmd.AddCommand(NewSendCredentialsCommand())
+	rootCmd.AddCommand(NewHandoutsCommand())
+	rootCmd.AddCommand(NewLockWorkshopCommand())
+	rootCmd.AddCommand(NewUnlockWorkshopCommand())
+	rootCmd.AddCommand(NewRotateCredentialsCommand())
+	rootCmd.AddCommand(NewOAuthAppCommand())
+	rootCmd.AddCommand(NewRenderCommand())
+	rootCmd.AddCommand(NewSetupDroneCommand())
+	rootCmd.AddCommand(NewValidatePipelinesCommand())
+	rootCmd.AddCommand(NewGenerateCommand())
+	rootCmd.AddCommand(NewControllerCommand())
 
 	return rootCmd
 }
@@ -0,0 +1,276 @@
+package commands
+
+import (
+	"fmt"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//RotateCredentialsOptions the options to rotate the credentials of the participants
+type RotateCredentialsOptions struct {
+	configFile      string
+	kubeconfig      string
+	credentialsFile string
+	users           []string
+	passwords       bool
+	oAuthSecrets    bool
+	accessTokens    bool
+	kubeconfigs     bool
+}
+
+// RotateCredentialsOptions implements Interface
+var _ Command = (*RotateCredentialsOptions)(nil)
+
+var rotateCredentialsCommandExample = fmt.Sprintf(`
+  # Rotate the passwords and oAuth client secrets of all the participants
+  %[1]s rotate-credentials --workshop-file workshop.yaml --credentials-file credentials.yaml
+  # Rotate only the credentials of user-03 including the access tokens and update the kubernetes secret
+  %[1]s rotate-credentials -f workshop.yaml -c credentials.yaml -u user-03 --access-tokens -k ~/.kube/config
+  # Rotate only the oAuth client secrets
+  %[1]s rotate-credentials -f workshop.yaml --passwords=false
+  # Reissue only the participant kubeconfigs, e.g. when their tokens expired
+  %[1]s rotate-credentials -f workshop.yaml -c credentials.yaml --passwords=false --oauth-secrets=false --kubeconfigs
+`, ExamplePrefix())
+
+//NewRotateCredentialsCommand instantiates the new instance of the RotateCredentialsCommand
+func NewRotateCredentialsCommand() *cobra.Command {
+	rotateOpts := &RotateCredentialsOptions{}
+
+	rotateCmd := &cobra.Command{
+		Use:     "rotate-credentials",
+		Short:   "Rotate the passwords, oAuth client secrets, access tokens and kubeconfigs of the participants",
+		Example: rotateCredentialsCommandExample,
+		RunE:    rotateOpts.Execute,
+		PreRunE: rotateOpts.Validate,
+	}
+
+	rotateOpts.AddFlags(rotateCmd)
+
+	return rotateCmd
+}
+
+// AddFlags implements Command
+func (opts *RotateCredentialsOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.kubeconfig, "kubeconfig", "k", "", "The kubeconfig file to use")
+	cmd.Flags().StringVarP(&opts.credentialsFile, "credentials-file", "c", "", "The file to store the rotated credentials in, defaults to the credentialsFile of the workshop")
+	cmd.Flags().StringSliceVarP(&opts.users, "user", "u", nil, "Rotate the credentials only of these participants e.g. user-01")
+	cmd.Flags().BoolVar(&opts.passwords, "passwords", true, "Reset the passwords of the participants to new random ones")
+	cmd.Flags().BoolVar(&opts.oAuthSecrets, "oauth-secrets", true, "Regenerate the client secrets of the participant oAuth applications")
+	cmd.Flags().BoolVar(&opts.accessTokens, "access-tokens", false, "Recreate the access tokens of the participants")
+	cmd.Flags().BoolVar(&opts.kubeconfigs, "kubeconfigs", false, "Reissue the kubeconfigs of the participants with new service account tokens")
+}
+
+// Validate implements Command
+func (opts *RotateCredentialsOptions) Validate(cmd *cobra.Command, args []string) error {
+	if !opts.passwords && !opts.oAuthSecrets && !opts.accessTokens && !opts.kubeconfigs {
+		return fmt.Errorf("nothing to rotate, enable at least one of passwords, oauth-secrets, access-tokens or kubeconfigs")
+	}
+	return nil
+}
+
+// Execute implements Command
+func (opts *RotateCredentialsOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	if opts.credentialsFile != "" {
+		workshopOpts.CredentialsFile = opts.credentialsFile
+	}
+	//the random passwords and new tokens can't be derived again, they would be lost without a credentials file
+	if (opts.passwords || opts.accessTokens || opts.kubeconfigs) && workshopOpts.CredentialsFile == "" {
+		return fmt.Errorf("require a credentials file to store the rotated credentials of the workshop %s", opts.configFile)
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	var creds map[string]*Participant
+	if workshopOpts.CredentialsFile != "" {
+		if creds, err = loadCredentials(workshopOpts.CredentialsFile); err != nil {
+			return err
+		}
+	}
+
+	if opts.oAuthSecrets && workshopOpts.GiteaUsers.SharedOAuthApps {
+		if err := opts.rotateOAuthSecret(workshopOpts, c, nil); err != nil {
+			return err
+		}
+	}
+
+	only := make(map[string]bool)
+	for _, u := range opts.users {
+		only[u] = true
+	}
+
+	giteaUsers := workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		userName := participantUserName(i)
+		if len(only) > 0 && !only[userName] {
+			continue
+		}
+		if _, resp, err := c.GetUserInfo(userName); err != nil {
+			if isNotFound(resp) {
+				log.Warnf("User %s is not provisioned, skipping", userName)
+				continue
+			}
+			return err
+		}
+
+		p, err := workshopOpts.participantCredentials(c, i)
+		if err != nil {
+			return err
+		}
+
+		//save what was rotated so far before failing, the old credentials are no longer valid
+		err = opts.rotate(workshopOpts, c, p)
+		if creds != nil {
+			creds[p.UserName] = p
+			if err := saveCredentials(workshopOpts.CredentialsFile, creds); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := workshopOpts.sendCredentials(p); err != nil {
+			log.Errorf("Error sending the rotated credentials to %s, resend them using send-credentials, %v", p.Email, err)
+		}
+	}
+
+	return nil
+}
+
+//rotate rotates the credentials of the participant updating the participant with the new ones
+func (opts *RotateCredentialsOptions) rotate(workshopOpts *WorkshopOptions, c *gitea.Client, p *Participant) error {
+	if opts.passwords {
+		password, err := randomHex(8)
+		if err != nil {
+			return err
+		}
+		mustChangePassword := false
+		if _, err := c.AdminEditUser(p.UserName, gitea.EditUserOption{
+			//required by the Gitea API, the participants are local users
+			LoginName:          p.UserName,
+			Password:           password,
+			MustChangePassword: &mustChangePassword,
+		}); err != nil {
+			return err
+		}
+		p.Password = password
+		log.Infof("Reset the password of user %s", p.UserName)
+	}
+
+	//the shared oAuth applications are rotated once for all the participants
+	if opts.oAuthSecrets && !workshopOpts.GiteaUsers.SharedOAuthApps {
+		if err := opts.rotateOAuthSecret(workshopOpts, c, p); err != nil {
+			return err
+		}
+	}
+
+	if opts.accessTokens {
+		tokens, err := workshopOpts.recreateAccessTokens(p)
+		if err != nil {
+			return err
+		}
+		p.AccessTokens = tokens
+	}
+
+	if opts.kubeconfigs {
+		kubeconfig, err := workshopOpts.participantKubeconfig(p, opts.kubeconfig)
+		if err != nil {
+			return err
+		}
+		p.Kubeconfig = kubeconfig
+	}
+
+	if opts.passwords {
+		return workshopOpts.applyCredentialSecrets(p, opts.kubeconfig)
+	}
+
+	return nil
+}
+
+//rotateOAuthSecret regenerates the client secrets of the participant oAuth applications, or of the shared ones
+//when the participant is nil, and updates their Kubernetes secrets,
+//Gitea generates a new client secret whenever the application is updated
+func (opts *RotateCredentialsOptions) rotateOAuthSecret(workshopOpts *WorkshopOptions, c *gitea.Client, p *Participant) error {
+	if owner := workshopOpts.GiteaUsers.oAuthAppOwner(p); owner != nil {
+		c.SetSudo(owner.UserName)
+		//Set it back to admin
+		defer c.SetSudo(workshopOpts.GiteaAdminUser)
+	}
+
+	oAuthApps, err := workshopOpts.participantOAuthApps(p, opts.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	for _, oauthOpts := range oAuthApps {
+		o, err := findOAuthApp(c, oauthOpts.oAuthAppName)
+		if err != nil {
+			return err
+		}
+		if o == nil {
+			log.Warnf("oAuth application %s does not exist, skipping", oauthOpts.oAuthAppName)
+			continue
+		}
+
+		//keep the redirect URIs the application has, they might have been changed using oauthapp update
+		oauthOpts.appRedirectURLs = o.RedirectURIs
+		if _, err := oauthOpts.updateApp(o); err != nil {
+			return err
+		}
+		log.Infof("Regenerated the client secret of oAuth application %s", o.Name)
+	}
+
+	return nil
+}
+
+//recreateAccessTokens deletes the access tokens of the participant and creates new ones with the same names,
+//the Gitea API allows managing the tokens only with the basic auth of the participant
+func (opts *WorkshopOptions) recreateAccessTokens(p *Participant) (map[string]string, error) {
+	c, err := gitea.NewClient(opts.GiteaURL, gitea.SetBasicAuth(p.UserName, p.Password), gitea.SetHTTPClient(giteaHTTPClient))
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []*gitea.AccessToken
+	opt := gitea.ListAccessTokensOptions{ListOptions: gitea.ListOptions{Page: 1, PageSize: 50}}
+	for {
+		page, _, err := c.ListAccessTokens(opt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, page...)
+		if len(page) < opt.PageSize {
+			break
+		}
+		opt.Page++
+	}
+
+	recreated := make(map[string]string)
+	for _, t := range tokens {
+		if _, err := c.DeleteAccessToken(t.ID); err != nil {
+			return nil, err
+		}
+		nt, _, err := c.CreateAccessToken(gitea.CreateAccessTokenOption{Name: t.Name})
+		if err != nil {
+			return nil, err
+		}
+		recreated[nt.Name] = nt.Token
+		log.Infof("Recreated access token %s of user %s", nt.Name, p.UserName)
+	}
+
+	return recreated, nil
+}
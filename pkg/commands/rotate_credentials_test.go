@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+func TestRotateCredentials(t *testing.T) {
+	edited := make(map[string]gitea.EditUserOption)
+	var updated oAuth2Option
+	deleted := false
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/users/user-01": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"login":"user-01"}`)
+		},
+		"/api/v1/users/user-02": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"/api/v1/admin/users/user-01": fakeAdminEditUser(t, edited),
+		"/api/v1/repos/user-01/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","clone_url":"http://gitea/user-01/jar-stack.git"}`)
+		},
+		"/api/v1/user/applications/oauth2": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Sudo") != "user-01" {
+				t.Errorf("Expecting the oAuth applications to be listed as user-01 but got %q", r.Header.Get("Sudo"))
+			}
+			fmt.Fprint(w, `[{"id":7,"name":"demo-oauth-user-01","client_id":"cid","redirect_uris":["http://drone/login"]}]`)
+		},
+		"/api/v1/user/applications/oauth2/7": func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+				t.Errorf("%v", err)
+			}
+			fmt.Fprint(w, `{"id":7,"name":"demo-oauth-user-01","client_id":"cid","client_secret":"new-secret"}`)
+		},
+		"/api/v1/users/user-01/tokens": func(w http.ResponseWriter, r *http.Request) {
+			if u, p, _ := r.BasicAuth(); u != "user-01" || p != edited["user-01"].Password {
+				t.Errorf("Expecting the tokens to be managed with the new password of user-01 but got %s:%s", u, p)
+			}
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id":4,"name":"ci","sha1":"new-token"}`)
+				return
+			}
+			fmt.Fprint(w, `[{"id":3,"name":"ci"}]`)
+		},
+		"/api/v1/users/user-01/tokens/3": func(w http.ResponseWriter, r *http.Request) {
+			deleted = r.Method == http.MethodDelete
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.yaml")
+	workshopFile := writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.GiteaUsers.OAuthAppName = "demo-oauth"
+		o.CredentialsFile = credentialsFile
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"rotate-credentials", "-f", workshopFile, "--access-tokens"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	password := edited["user-01"].Password
+	if len(edited) != 1 || password == "" || password == "user-01@123" {
+		t.Errorf("Expecting only the password of user-01 to be reset to a random one but got %v", edited)
+	}
+	if updated.Name != "demo-oauth-user-01" || !updated.ConfidentialClient || len(updated.RedirectURIs) != 1 || updated.RedirectURIs[0] != "http://drone/login" {
+		t.Errorf("Expecting the oAuth application to be updated with its name and redirect URIs but got %v", updated)
+	}
+	if !deleted {
+		t.Errorf("Expecting the old access token to be deleted")
+	}
+
+	creds, err := loadCredentials(credentialsFile)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(creds) != 1 || creds["user-01"].Password != password || creds["user-01"].AccessTokens["ci"] != "new-token" {
+		t.Errorf("Expecting the rotated credentials of user-01 to be stored but got %v", creds)
+	}
+
+	workshopOpts, err := loadWorkshopOptions(workshopFile)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	p, err := workshopOpts.participantCredentials(c, 1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if p.Password != password {
+		t.Errorf("Expecting the participant credentials to use the rotated password but got %s", p.Password)
+	}
+}
+
+func TestRotateCredentialsRequiresCredentialsFile(t *testing.T) {
+	s := newFakeGitea(t, nil)
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"rotate-credentials", "-f", writeWorkshopFile(t, s.URL)})
+	if err := rootCmd.Execute(); err == nil {
+		t.Errorf("Expecting rotating the passwords without a credentials file to fail")
+	}
+}
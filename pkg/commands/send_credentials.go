@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//SendCredentialsOptions the options to email the credentials to the provisioned participants
+type SendCredentialsOptions struct {
+	configFile string
+	users      []string
+}
+
+// SendCredentialsOptions implements Interface
+var _ Command = (*SendCredentialsOptions)(nil)
+
+var sendCredentialsCommandExample = fmt.Sprintf(`
+  # Email the credentials to all the participants using the smtp settings of the workshop
+  %[1]s send-credentials --workshop-file workshop.yaml
+  # Resend the credentials only to user-03
+  %[1]s send-credentials -f workshop.yaml --user user-03
+`, ExamplePrefix())
+
+//NewSendCredentialsCommand instantiates the new instance of the SendCredentialsCommand
+func NewSendCredentialsCommand() *cobra.Command {
+	sendOpts := &SendCredentialsOptions{}
+
+	sendCmd := &cobra.Command{
+		Use:     "send-credentials",
+		Short:   "Email the credentials to the provisioned participants",
+		Example: sendCredentialsCommandExample,
+		RunE:    sendOpts.Execute,
+		PreRunE: sendOpts.Validate,
+	}
+
+	sendOpts.AddFlags(sendCmd)
+
+	return sendCmd
+}
+
+// AddFlags implements Command
+func (opts *SendCredentialsOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringSliceVarP(&opts.users, "user", "u", nil, "Send the credentials only to these participants e.g. user-01")
+}
+
+// Validate implements Command
+func (opts *SendCredentialsOptions) Validate(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// Execute implements Command
+func (opts *SendCredentialsOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	if workshopOpts.SMTP == nil || workshopOpts.SMTP.Host == "" {
+		return fmt.Errorf("the workshop file %s has no smtp host configured", opts.configFile)
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	only := make(map[string]bool)
+	for _, u := range opts.users {
+		only[u] = true
+	}
+
+	giteaUsers := workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		userName := participantUserName(i)
+		if len(only) > 0 && !only[userName] {
+			continue
+		}
+		if _, resp, err := c.GetUserInfo(userName); err != nil {
+			if isNotFound(resp) {
+				log.Warnf("User %s is not provisioned, skipping", userName)
+				continue
+			}
+			return err
+		}
+
+		p, err := workshopOpts.participantCredentials(c, i)
+		if err != nil {
+			return err
+		}
+		if err := workshopOpts.sendCredentials(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
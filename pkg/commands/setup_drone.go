@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//DroneSetup is the configuration of the Drone repos of the participants, activated with the Drone REST API
+type DroneSetup struct {
+	//Server is the Go template of the Drone server URL, defaults to the Drone server of the participant
+	Server string `yaml:"server,omitempty"`
+	//Token is the Go template of the Drone API token of the participant,
+	//defaults to the admin token of the Drone server deployed in the participant namespace
+	Token string `yaml:"token,omitempty"`
+	//Trusted marks the repos trusted, allowing privileged pipeline steps
+	Trusted bool `yaml:"trusted,omitempty"`
+	//Protected marks the repos protected, changes to the pipelines must be approved
+	Protected bool `yaml:"protected,omitempty"`
+	//Secrets are the secrets created in each repo
+	Secrets []DroneSecret `yaml:"secrets,omitempty"`
+}
+
+//DroneSecret is a Drone repo secret
+type DroneSecret struct {
+	Name string `yaml:"name"`
+	//Value is the Go template of the secret value rendered with the Participant e.g. {{ .Password }}
+	Value string `yaml:"value"`
+	//PullRequest exposes the secret to the pull request builds
+	PullRequest bool `yaml:"pullRequest,omitempty"`
+}
+
+//SetupDroneOptions the options to activate the Drone repos of the participants
+type SetupDroneOptions struct {
+	configFile      string
+	kubeconfig      string
+	credentialsFile string
+	users           []string
+}
+
+// SetupDroneOptions implements Interface
+var _ Command = (*SetupDroneOptions)(nil)
+
+var setupDroneCommandExample = fmt.Sprintf(`
+  # Activate the repos of all the participants and create their secrets on their Drone servers
+  %[1]s setup-drone --workshop-file workshop.yaml -k ~/.kube/config
+  # Activate only the repos of user-03
+  %[1]s setup-drone -f workshop.yaml -u user-03
+`, ExamplePrefix())
+
+//NewSetupDroneCommand instantiates the new instance of the SetupDroneCommand
+func NewSetupDroneCommand() *cobra.Command {
+	setupDroneOpts := &SetupDroneOptions{}
+
+	setupDroneCmd := &cobra.Command{
+		Use:     "setup-drone",
+		Short:   "Activate the participant repos and create their secrets on Drone",
+		Example: setupDroneCommandExample,
+		RunE:    setupDroneOpts.Execute,
+		PreRunE: setupDroneOpts.Validate,
+	}
+
+	setupDroneOpts.AddFlags(setupDroneCmd)
+
+	return setupDroneCmd
+}
+
+// AddFlags implements Command
+func (opts *SetupDroneOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.kubeconfig, "kubeconfig", "k", "", "The kubeconfig file to read the Drone admin tokens with")
+	cmd.Flags().StringVarP(&opts.credentialsFile, "credentials-file", "c", "", "The file with the rotated credentials, defaults to the credentialsFile of the workshop")
+	cmd.Flags().StringSliceVarP(&opts.users, "user", "u", nil, "Activate the repos only of these participants e.g. user-01")
+}
+
+// Validate implements Command
+func (opts *SetupDroneOptions) Validate(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// Execute implements Command
+func (opts *SetupDroneOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+	if workshopOpts.GiteaUsers.Drone == nil {
+		return fmt.Errorf("no drone setup in the workshop %s", opts.configFile)
+	}
+
+	if opts.credentialsFile != "" {
+		workshopOpts.CredentialsFile = opts.credentialsFile
+	}
+
+	only := make(map[string]bool)
+	for _, u := range opts.users {
+		only[u] = true
+	}
+
+	giteaUsers := workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		p := giteaUsers.participant(i)
+		if len(only) > 0 && !only[p.UserName] {
+			continue
+		}
+		//the secrets might refer to the rotated credentials
+		if err := workshopOpts.storedCredentials(p); err != nil {
+			return err
+		}
+		if err := workshopOpts.setupDrone(p, opts.kubeconfig); err != nil {
+			return fmt.Errorf("error setting up the Drone repos of %s, %v", p.UserName, err)
+		}
+	}
+
+	return nil
+}
+
+//setupDrone syncs and activates the Drone repos of the participant, marks them trusted or protected
+//and creates their secrets, the participant must have signed in to Drone once for it to access their Gitea repos
+func (opts *WorkshopOptions) setupDrone(p *Participant, kubeconfig string) error {
+	giteaUsers := opts.GiteaUsers
+	ds := giteaUsers.Drone
+
+	d, err := opts.droneClient(p, kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	u, err := d.self()
+	if err != nil {
+		return err
+	}
+	//a Drone admin token can manage the repos the participant synced when signing in
+	if u.Login == p.UserName {
+		if u.LastLogin == 0 {
+			log.Warnf("User %s has not signed in to Drone %s yet, skipping", p.UserName, d.server)
+			return nil
+		}
+		if err := d.syncRepos(); err != nil {
+			return err
+		}
+	}
+
+	var patch droneRepoPatch
+	if ds.Trusted {
+		patch.Trusted = &ds.Trusted
+	}
+	if ds.Protected {
+		patch.Protected = &ds.Protected
+	}
+
+	for _, repoURL := range giteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return err
+		}
+		repo, err := d.repo(p.UserName, repoName)
+		if err != nil {
+			if isDroneNotFound(err) {
+				log.Warnf("Repo %s/%s is not synced to Drone %s, skipping", p.UserName, repoName, d.server)
+				continue
+			}
+			return err
+		}
+
+		if !repo.Active {
+			if err := d.activateRepo(p.UserName, repoName); err != nil {
+				return err
+			}
+			log.Infof("Activated Drone repo %s", repo.Slug)
+		}
+
+		if patch.Trusted != nil || patch.Protected != nil {
+			if err := d.updateRepo(p.UserName, repoName, patch); err != nil {
+				return err
+			}
+		}
+
+		for _, s := range ds.Secrets {
+			value, err := renderTemplate(s.Value, p)
+			if err != nil {
+				return err
+			}
+			if err := d.applyRepoSecret(p.UserName, repoName, droneRepoSecret{
+				Name:        s.Name,
+				Data:        value,
+				PullRequest: s.PullRequest,
+			}); err != nil {
+				return err
+			}
+			log.Infof("Set secret %s of Drone repo %s", s.Name, repo.Slug)
+		}
+	}
+
+	return nil
+}
+
+//droneClient returns the client of the Drone server of the participant
+func (opts *WorkshopOptions) droneClient(p *Participant, kubeconfig string) (*droneClient, error) {
+	ds := opts.GiteaUsers.Drone
+
+	server := opts.droneURL(p)
+	if ds.Server != "" {
+		var err error
+		if server, err = renderTemplate(ds.Server, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if ds.Token != "" {
+		token, err := renderTemplate(ds.Token, p)
+		if err != nil {
+			return nil, err
+		}
+		return newDroneClient(server, token), nil
+	}
+
+	//the participant is the admin of the Drone server deployed in their namespace
+	clientset, err := newKubernetesClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := clientset.CoreV1().Secrets(p.Namespace).Get(context.TODO(), droneAdminSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return newDroneClient(server, string(secret.Data[droneAdminTokenKey])), nil
+}
+
+//validateDroneSetup checks the templates of the Drone setup render for a participant
+func (u GiteaUser) validateDroneSetup() error {
+	ds := u.Drone
+	if ds == nil {
+		return nil
+	}
+	if ds.Token == "" && u.DroneServers == nil {
+		return fmt.Errorf("the drone setup requires a token when the workshop deploys no Drone servers")
+	}
+	p := u.participant(u.From)
+	texts := []string{ds.Server, ds.Token}
+	for _, s := range ds.Secrets {
+		if s.Name == "" {
+			return fmt.Errorf("the name of the Drone secret is empty")
+		}
+		texts = append(texts, s.Value)
+	}
+	for _, text := range texts {
+		if _, err := renderTemplate(text, p); err != nil {
+			return fmt.Errorf("invalid drone setup, %v", err)
+		}
+	}
+	return nil
+}
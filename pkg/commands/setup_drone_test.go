@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//fakeDrone is a stand-in of the Drone REST API recording the repo changes
+type fakeDrone struct {
+	sync.Mutex
+	lastLogin int64
+	synced    bool
+	repos     map[string]*droneRepo
+	secrets   map[string]droneRepoSecret
+	//patched are the names of the updated secrets
+	patched []string
+}
+
+func newFakeDrone(t *testing.T, lastLogin int64, repos ...string) (*fakeDrone, *httptest.Server) {
+	d := &fakeDrone{
+		lastLogin: lastLogin,
+		repos:     make(map[string]*droneRepo),
+		secrets:   make(map[string]droneRepoSecret),
+	}
+	for _, slug := range repos {
+		d.repos[slug] = &droneRepo{Slug: slug}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer drone-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"login":"user-01","last_login":%d}`, d.lastLogin)
+	})
+	mux.HandleFunc("/api/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		d.Lock()
+		d.synced = r.Method == http.MethodPost
+		d.Unlock()
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/api/repos/user-01/jar-stack", func(w http.ResponseWriter, r *http.Request) {
+		d.Lock()
+		defer d.Unlock()
+		repo, ok := d.repos["user-01/jar-stack"]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			repo.Active = true
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(repo); err != nil {
+				t.Errorf("%v", err)
+			}
+		}
+		if err := json.NewEncoder(w).Encode(repo); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+	mux.HandleFunc("/api/repos/user-01/jar-stack/secrets", func(w http.ResponseWriter, r *http.Request) {
+		var s droneRepoSecret
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			t.Errorf("%v", err)
+		}
+		d.Lock()
+		d.secrets[s.Name] = s
+		d.Unlock()
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/api/repos/user-01/jar-stack/secrets/", func(w http.ResponseWriter, r *http.Request) {
+		d.Lock()
+		defer d.Unlock()
+		name := strings.TrimPrefix(r.URL.Path, "/api/repos/user-01/jar-stack/secrets/")
+		s, ok := d.secrets[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPatch {
+			if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+				t.Errorf("%v", err)
+			}
+			d.secrets[name] = s
+			d.patched = append(d.patched, name)
+		}
+		//the data is never returned
+		s.Data = ""
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return d, s
+}
+
+//droneSetupWorkshop returns the workshop file setting up the Drone repos of user-01 on the Drone server
+func droneSetupWorkshop(t *testing.T, droneURL string) string {
+	return writeWorkshopFile(t, "http://gitea-127.0.0.1.sslip.io:30950", func(o *WorkshopOptions) {
+		o.GiteaUsers.To = 1
+		o.GiteaUsers.Drone = &DroneSetup{
+			Server:  droneURL,
+			Token:   "drone-token",
+			Trusted: true,
+			Secrets: []DroneSecret{
+				{Name: "gitea_password", Value: "{{ .Password }}"},
+				{Name: "registry", Value: "registry.example.com/{{ .UserName }}", PullRequest: true},
+			},
+		}
+	})
+}
+
+func TestSetupDrone(t *testing.T) {
+	d, s := newFakeDrone(t, 1660000000, "user-01/jar-stack")
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"setup-drone", "-f", droneSetupWorkshop(t, s.URL)})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if !d.synced {
+		t.Errorf("Expecting the repos of the participant to be synced")
+	}
+	repo := d.repos["user-01/jar-stack"]
+	if !repo.Active || !repo.Trusted || repo.Protected {
+		t.Errorf("Expecting the repo to be activated and trusted but got %v", repo)
+	}
+	expected := map[string]droneRepoSecret{
+		"gitea_password": {Name: "gitea_password", Data: "user-01@123"},
+		"registry":       {Name: "registry", Data: "registry.example.com/user-01", PullRequest: true},
+	}
+	if !reflect.DeepEqual(expected, d.secrets) {
+		t.Errorf("Expecting the repo secrets %v but got %v", expected, d.secrets)
+	}
+}
+
+func TestSetupDroneExistingSecret(t *testing.T) {
+	d, s := newFakeDrone(t, 1660000000, "user-01/jar-stack")
+	d.secrets["gitea_password"] = droneRepoSecret{Name: "gitea_password", Data: "user-01@old"}
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"setup-drone", "-f", droneSetupWorkshop(t, s.URL)})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if expected := []string{"gitea_password"}; !reflect.DeepEqual(expected, d.patched) {
+		t.Errorf("Expecting only the existing secret to be updated but got %v", d.patched)
+	}
+	if secret := d.secrets["gitea_password"]; secret.Data != "user-01@123" {
+		t.Errorf("Expecting the existing secret to be updated with the password but got %v", secret)
+	}
+	if _, ok := d.secrets["registry"]; !ok {
+		t.Errorf("Expecting the missing secret to be created")
+	}
+}
+
+func TestDroneClientTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	t.Cleanup(s.Close)
+	d := newDroneClient(s.URL, "drone-token")
+	d.httpClient = &http.Client{Timeout: 50 * time.Millisecond}
+	if _, err := d.self(); err == nil {
+		t.Errorf("Expecting the request to a hung Drone server to time out")
+	}
+}
+
+func TestSetupDroneNotSignedIn(t *testing.T) {
+	d, s := newFakeDrone(t, 0, "user-01/jar-stack")
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"setup-drone", "-f", droneSetupWorkshop(t, s.URL)})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if d.synced || d.repos["user-01/jar-stack"].Active {
+		t.Errorf("Expecting the participant that has not signed in to Drone to be skipped")
+	}
+}
+
+func TestSetupDroneNotSynced(t *testing.T) {
+	d, s := newFakeDrone(t, 1660000000)
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"setup-drone", "-f", droneSetupWorkshop(t, s.URL)})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(d.secrets) != 0 {
+		t.Errorf("Expecting no secrets to be created for a repo missing in Drone but got %v", d.secrets)
+	}
+}
+
+func TestValidateDroneSetup(t *testing.T) {
+	giteaUsers := GiteaUser{From: 1, To: 1, Drone: &DroneSetup{}}
+	if err := giteaUsers.validateDroneSetup(); err == nil {
+		t.Errorf("Expecting a Drone setup without token and Drone servers to be invalid")
+	}
+	giteaUsers.Drone = &DroneSetup{Token: "t", Secrets: []DroneSecret{{Name: "s", Value: "{{ .Secret }}"}}}
+	if err := giteaUsers.validateDroneSetup(); err == nil {
+		t.Errorf("Expecting a secret template referring to an unknown field to be invalid")
+	}
+}
@@ -3,8 +3,8 @@ package commands
 import (
 	"fmt"
 	"io/ioutil"
+	"time"
 
-	"code.gitea.io/sdk/gitea"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	yamlv2 "gopkg.in/yaml.v2"
@@ -12,8 +12,10 @@ import (
 
 //WorkshopSetupOptions the configuration data for workshop
 type WorkshopSetupOptions struct {
-	configFile string
-	kubeconfig string
+	configFile             string
+	kubeconfig             string
+	skipPipelineValidation bool
+	shardCount             int
 }
 
 //WorkshopOptions the configuration data for workshop
@@ -22,18 +24,59 @@ type WorkshopOptions struct {
 	GiteaAdminUser     string    `yaml:"giteaAdminUserName,omitempty"`
 	GiteaURL           string    `yaml:"giteaURL,omitempty"`
 	GiteaUsers         GiteaUser `yaml:"users"`
+	SMTP               *SMTP     `yaml:"smtp,omitempty"`
+	//ExpiresAt is when the participant accounts are locked by lock-workshop --if-expired
+	ExpiresAt *time.Time `yaml:"expiresAt,omitempty"`
+	//CredentialsFile is where setup-workshop stores the participant credentials and kubeconfigs,
+	//and where rotate-credentials stores the rotated ones
+	CredentialsFile string `yaml:"credentialsFile,omitempty"`
 }
 
 //GiteaUser is a Gitea user
 type GiteaUser struct {
-	From                int      `yaml:"from"`
-	To                  int      `yaml:"to"`
-	AddKubernetesSecret bool     `yaml:"addKubernetesSecret"`
-	Namespace           string   `yaml:"namespace"`
-	OAuthAppName        string   `yaml:"oAuthAppName"`
-	OAuthRedirectURI    string   `yaml:"oAuthRedirectURI"`
-	SecretNamespace     string   `yaml:"secretNamespace"`
-	Repos               []string `yaml:"repos"`
+	From                int        `yaml:"from"`
+	To                  int        `yaml:"to"`
+	AddKubernetesSecret bool       `yaml:"addKubernetesSecret"`
+	Namespace           string     `yaml:"namespace"`
+	OAuthAppName        string     `yaml:"oAuthAppName"`
+	OAuthRedirectURI    string     `yaml:"oAuthRedirectURI"`
+	SecretNamespace     string     `yaml:"secretNamespace"`
+	Repos               []string   `yaml:"repos"`
+	Roster              []Attendee `yaml:"roster,omitempty"`
+	//OAuthRedirectURIs are the Go templates of the participant oAuth application redirect URIs
+	//e.g. https://drone-{{ .UserName }}.example.com/login, defaults to <oAuthRedirectURI>/login
+	OAuthRedirectURIs []string `yaml:"oAuthRedirectURIs,omitempty"`
+	//OAuthConfidentialClient makes the participant oAuth applications confidential clients, defaults to true
+	OAuthConfidentialClient *bool `yaml:"oAuthConfidentialClient,omitempty"`
+	//OAuthApps are the oAuth applications of each participant, when set the oAuthAppName,
+	//oAuthRedirectURI(s), oAuthConfidentialClient, secretName, secretProfile and secretKeys are ignored
+	OAuthApps []OAuthApp `yaml:"oAuthApps,omitempty"`
+	//SharedOAuthApps creates the oAuth applications and their secrets once, owned by the admin user,
+	//e.g. for a central Drone server all the participants sign in to
+	SharedOAuthApps bool `yaml:"sharedOAuthApps,omitempty"`
+	//SecretName is the Go template of the oAuth application secret name, defaults to <oAuth app name>-secret
+	SecretName string `yaml:"secretName,omitempty"`
+	//SecretProfile is the secret profile of the CI server using the oAuth application, defaults to drone
+	SecretProfile string `yaml:"secretProfile,omitempty"`
+	//SecretKeys are the keys of the oAuth application secret, overriding the keys of the secret profile
+	SecretKeys *SecretKeys `yaml:"secretKeys,omitempty"`
+	//ArgoCDRepoSecrets creates an Argo CD repository secret for each participant repo when set
+	ArgoCDRepoSecrets *ArgoCDRepoSecrets `yaml:"argoCDRepoSecrets,omitempty"`
+	//TektonGitSecrets creates a basic-auth git credential secret and a service account for each participant when set
+	TektonGitSecrets *TektonGitSecrets `yaml:"tektonGitSecrets,omitempty"`
+	//ParticipantNamespaces creates a namespace for each participant when set, the oAuth application
+	//secrets of the participant are created in it instead of the secretNamespace
+	ParticipantNamespaces *ParticipantNamespaces `yaml:"participantNamespaces,omitempty"`
+	//DroneServers deploys a Drone server and runner in each participant namespace when set
+	DroneServers *DroneServers `yaml:"droneServers,omitempty"`
+	//Drone activates the participant repos and creates their secrets on Drone with setup-drone
+	Drone *DroneSetup `yaml:"drone,omitempty"`
+}
+
+//Attendee is the person attending the workshop as one of the participants
+type Attendee struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
 }
 
 // WorkshopOptions implements Interface
@@ -44,6 +87,8 @@ var workshopCommandExample = fmt.Sprintf(`
   %[1]s setup-workshop --workshop-file my-app
   # Create oAuthApp and store the client id and secret in kubernetes secret
   %[1]s setup-workshop --app-name my-app  -k ~/.kube/config
+  # Provision the slice of the participants of the JOB_COMPLETION_INDEX of a 4 pod Indexed Job
+  %[1]s setup-workshop --workshop-file /config/workshop.yaml --shard-count 4
 `, ExamplePrefix())
 
 //NewWorkshopSetupCommand instantiates the new instance of the NewWorkshopSetupCommand
@@ -70,23 +115,34 @@ func (opts *WorkshopSetupOptions) AddFlags(cmd *cobra.Command) {
 		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
 	}
 	cmd.Flags().StringVarP(&opts.kubeconfig, "kubeconfig", "k", "", "The kubeconfig file to use")
+	cmd.Flags().BoolVar(&opts.skipPipelineValidation, "skip-pipeline-validation", false, "Provision the participants without validating the Drone pipelines of the templates")
+	cmd.Flags().IntVar(&opts.shardCount, "shard-count", 1, "The number of pods of the Indexed Job, each provisioning the slice of the participants of its JOB_COMPLETION_INDEX")
 }
 
 // Execute implements Command
 func (opts *WorkshopSetupOptions) Execute(cmd *cobra.Command, args []string) error {
-	var workshopOpts WorkshopOptions
-	b, err := ioutil.ReadFile(opts.configFile)
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
 	if err != nil {
 		return err
 	}
-	err = yamlv2.Unmarshal(b, &workshopOpts)
+
+	s, err := newShard(opts.shardCount)
 	if err != nil {
 		return err
 	}
 
-	log.Debugf("%#v", workshopOpts)
+	//a broken template pipeline would be copied to every participant
+	if !opts.skipPipelineValidation {
+		c, err := workshopOpts.newGiteaClient()
+		if err != nil {
+			return err
+		}
+		if err := workshopOpts.validateDronePipelines(c); err != nil {
+			return err
+		}
+	}
 
-	_, err = workshopOpts.createUsers(opts.kubeconfig)
+	_, err = workshopOpts.createUsers(opts.kubeconfig, s)
 
 	if err != nil {
 		return err
@@ -95,81 +151,75 @@ func (opts *WorkshopSetupOptions) Execute(cmd *cobra.Command, args []string) err
 	return nil
 }
 
-func (opts *WorkshopOptions) createUsers(kubeconfig string) ([]*gitea.User, error) {
-	log.Debugln("Creating users")
-	var err error
-	giteaUsers := opts.GiteaUsers
+//loadWorkshopOptions reads the workshop configuration from the configFile
+func loadWorkshopOptions(configFile string) (*WorkshopOptions, error) {
+	b, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return parseWorkshopOptions(b)
+}
 
-	gusers := make([]*gitea.User, giteaUsers.To)
+//parseWorkshopOptions parses and validates the YAML workshop configuration
+func parseWorkshopOptions(b []byte) (*WorkshopOptions, error) {
+	var workshopOpts WorkshopOptions
+	err := yamlv2.Unmarshal(b, &workshopOpts)
+	if err != nil {
+		return nil, err
+	}
 
-	c, err := opts.newGiteaClient()
+	if err := workshopOpts.GiteaUsers.validateOAuthApps(); err != nil {
+		return nil, err
+	}
 
-	if err != nil {
+	if err := workshopOpts.GiteaUsers.validateParticipantNamespaces(); err != nil {
 		return nil, err
 	}
 
-	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
-		cp := false
-		userName := fmt.Sprintf("user-%02d", i)
-		userEmail := fmt.Sprintf("user-%02d@example.com", i)
-		userPassword := fmt.Sprintf("user-%02d@123", i)
+	if err := workshopOpts.GiteaUsers.validateDroneServers(); err != nil {
+		return nil, err
+	}
 
-		if u, _, err := c.GetUserInfo(userName); u != nil && err == nil {
-			log.Infof("User %s already exists", u.UserName)
-			continue
-		}
+	if err := workshopOpts.GiteaUsers.validateDroneSetup(); err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	log.Debugf("%#v", workshopOpts)
 
-		uOpt := gitea.CreateUserOption{
-			Username:           userName,
-			Email:              userEmail,
-			Password:           userPassword,
-			MustChangePassword: &cp,
-			SendNotify:         false,
-		}
+	return &workshopOpts, nil
+}
 
-		u, _, err := c.AdminCreateUser(uOpt)
+//createUsers provisions the participants of the shard, the existing ones are reconciled to what is missing of them
+func (opts *WorkshopOptions) createUsers(kubeconfig string, s shard) ([]*Participant, error) {
+	giteaUsers := opts.GiteaUsers
+	from, to := s.participants(giteaUsers)
+	log.Debugf("Creating users %d to %d of shard %d/%d", from, to, s.index+1, s.count)
 
-		if err != nil {
-			return nil, err
-		}
-		log.Infof("Created user with username %s", u.UserName)
-		gusers = append(gusers, u)
-
-		//Create oAuth2 App
-		c.SetSudo(u.UserName)
-
-		oauthOpts := OAuthAppOptions{
-			oAuthAppName:        fmt.Sprintf("%s-user-%02d", giteaUsers.OAuthAppName, i),
-			appRedirectURL:      fmt.Sprintf("%s/login", giteaUsers.OAuthRedirectURI),
-			addKubernetesSecret: giteaUsers.AddKubernetesSecret,
-			namespace:           giteaUsers.SecretNamespace,
-			kubeconfig:          kubeconfig,
-		}
+	var participants []*Participant
 
-		_, err = oauthOpts.createOAuthApp(c)
-		if err != nil {
+	c, err := opts.newGiteaClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if giteaUsers.SharedOAuthApps && s.first() {
+		if err := opts.createSharedOAuthApps(c, kubeconfig); err != nil {
 			return nil, err
 		}
+	}
 
-		for _, repoURL := range giteaUsers.Repos {
-			repoName, err := repoNameFromURL(repoURL)
-			if err != nil {
-				return nil, err
-			}
-			if err := createRepo(c, repoURL, u.UserName, repoName); err != nil {
-				return nil, err
-			}
-		}
+	for i := from; i <= to; i++ {
+		p := giteaUsers.participant(i)
 
-		//Set it back to admin
-		c.SetSudo(opts.GiteaAdminUser)
+		//an existing participant, e.g. half provisioned by a failed run, gets what is missing of it
+		if err := opts.reconcileParticipant(c, p, kubeconfig); err != nil {
+			return nil, err
+		}
+		participants = append(participants, p)
 	}
 
-	return gusers, nil
+	return participants, nil
 }
 
 // Validate implements Command
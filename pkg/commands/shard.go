@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+//jobCompletionIndexEnv is the environment variable Kubernetes sets to the index of the pod of an Indexed Job
+const jobCompletionIndexEnv = "JOB_COMPLETION_INDEX"
+
+//shard is the slice of the participants one pod of the setup job provisions
+type shard struct {
+	index int
+	count int
+}
+
+//newShard returns the shard of the pod, read from the JOB_COMPLETION_INDEX of the Indexed Job
+//when the participants are split in to more than one shard
+func newShard(count int) (shard, error) {
+	if count < 1 {
+		return shard{}, fmt.Errorf("the shard count must be at least 1 but is %d", count)
+	}
+	if count == 1 {
+		return shard{index: 0, count: 1}, nil
+	}
+	v, ok := os.LookupEnv(jobCompletionIndexEnv)
+	if !ok {
+		return shard{}, fmt.Errorf("%s is not set, run the %d shards as an Indexed Job", jobCompletionIndexEnv, count)
+	}
+	index, err := strconv.Atoi(v)
+	if err != nil {
+		return shard{}, fmt.Errorf("invalid %s %q, %v", jobCompletionIndexEnv, v, err)
+	}
+	if index < 0 || index >= count {
+		return shard{}, fmt.Errorf("the %s %d is not one of the %d shards", jobCompletionIndexEnv, index, count)
+	}
+	return shard{index: index, count: count}, nil
+}
+
+//participants returns the contiguous range of the participants of the shard, from is greater than to when
+//the shard has no participants e.g. when there are more shards than participants
+func (s shard) participants(u GiteaUser) (from, to int) {
+	if s.count <= 1 {
+		return u.From, u.To
+	}
+	size := (u.To - u.From + s.count) / s.count
+	from = u.From + s.index*size
+	to = from + size - 1
+	if to > u.To {
+		to = u.To
+	}
+	return from, to
+}
+
+//first is true for the shard that provisions what the participants share e.g. the shared oAuth applications
+func (s shard) first() bool {
+	return s.index == 0
+}
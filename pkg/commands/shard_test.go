@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestShardParticipants(t *testing.T) {
+	u := GiteaUser{From: 1, To: 10}
+	var ranges [][2]int
+	for i := 0; i < 3; i++ {
+		from, to := shard{index: i, count: 3}.participants(u)
+		ranges = append(ranges, [2]int{from, to})
+	}
+	expected := [][2]int{{1, 4}, {5, 8}, {9, 10}}
+	if !reflect.DeepEqual(expected, ranges) {
+		t.Errorf("Expecting the shards %v but got %v", expected, ranges)
+	}
+
+	if from, to := (shard{index: 3, count: 4}).participants(GiteaUser{From: 1, To: 2}); from <= to {
+		t.Errorf("Expecting the shard beyond the participants to be empty but got %d to %d", from, to)
+	}
+	if from, to := (shard{count: 1}).participants(u); from != 1 || to != 10 {
+		t.Errorf("Expecting a single shard to have all the participants but got %d to %d", from, to)
+	}
+}
+
+func TestNewShard(t *testing.T) {
+	if s, err := newShard(1); err != nil || s.count != 1 || !s.first() {
+		t.Errorf("Expecting a single shard without %s but got %v, %v", jobCompletionIndexEnv, s, err)
+	}
+	t.Setenv(jobCompletionIndexEnv, "2")
+	if s, err := newShard(4); err != nil || s.index != 2 {
+		t.Errorf("Expecting the shard index 2 but got %v, %v", s, err)
+	}
+	if _, err := newShard(2); err == nil {
+		t.Errorf("Expecting the index 2 of 2 shards to be invalid")
+	}
+	if _, err := newShard(0); err == nil {
+		t.Errorf("Expecting the shard count 0 to be invalid")
+	}
+}
+
+func TestShardResumesHalfProvisionedParticipant(t *testing.T) {
+	t.Setenv(jobCompletionIndexEnv, "1")
+	var migrated []string
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		//the failed pod created user-02 but not its repo
+		"/api/v1/users/user-02": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"id":3,"login":"user-02"}`)
+		},
+		"/api/v1/repos/user-02/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"/api/v1/repos/migrate": func(w http.ResponseWriter, r *http.Request) {
+			migrated = append(migrated, r.Header.Get("Sudo"))
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"name":"jar-stack"}`)
+		},
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"setup-workshop", "-f", writeWorkshopFile(t, s.URL), "--shard-count=2", "--skip-pipeline-validation"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if expected := []string{"user-02"}; !reflect.DeepEqual(expected, migrated) {
+		t.Errorf("Expecting the missing repo of the existing participant of the shard to be created but got %v", migrated)
+	}
+}
@@ -0,0 +1,280 @@
+package commands
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/mail"
+	"strings"
+	"sync"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//SignupOptions the options to serve the participant registration portal
+type SignupOptions struct {
+	configFile string
+	kubeconfig string
+	address    string
+	accessCode string
+}
+
+//signup provisions the next free participant slot for the attendees registering on the portal
+type signup struct {
+	workshopOpts *WorkshopOptions
+	client       *gitea.Client
+	kubeconfig   string
+	accessCode   string
+
+	//serializes the slot allocation and the provisioning, the sudo of the client is shared
+	mu sync.Mutex
+}
+
+// SignupOptions implements Interface
+var _ Command = (*SignupOptions)(nil)
+
+var signupCommandExample = fmt.Sprintf(`
+  # Serve the registration portal on http://localhost:8080
+  %[1]s serve signup --workshop-file workshop.yaml -k ~/.kube/config
+  # Require the attendees to enter the access code announced at the workshop
+  %[1]s serve signup -f workshop.yaml --access-code drone-2022
+`, ExamplePrefix())
+
+//NewSignupCommand instantiates the new instance of the SignupCommand
+func NewSignupCommand() *cobra.Command {
+	signupOpts := &SignupOptions{}
+
+	signupCmd := &cobra.Command{
+		Use:     "signup",
+		Short:   "Serve a registration portal where the attendees get the next free participant account",
+		Example: signupCommandExample,
+		RunE:    signupOpts.Execute,
+		PreRunE: signupOpts.Validate,
+	}
+
+	signupOpts.AddFlags(signupCmd)
+
+	return signupCmd
+}
+
+// AddFlags implements Command
+func (opts *SignupOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.kubeconfig, "kubeconfig", "k", "", "The kubeconfig file to use")
+	cmd.Flags().StringVarP(&opts.address, "address", "a", ":8080", "The address to listen on")
+	cmd.Flags().StringVar(&opts.accessCode, "access-code", "", "The code the attendees must enter to register")
+}
+
+// Validate implements Command
+func (opts *SignupOptions) Validate(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// Execute implements Command
+func (opts *SignupOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	if workshopOpts.GiteaUsers.SharedOAuthApps {
+		if err := workshopOpts.createSharedOAuthApps(c, opts.kubeconfig); err != nil {
+			return err
+		}
+	}
+
+	s := &signup{
+		workshopOpts: workshopOpts,
+		client:       c,
+		kubeconfig:   opts.kubeconfig,
+		accessCode:   opts.accessCode,
+	}
+
+	return listenAndServe(opts.address, s.handler())
+}
+
+//handler returns the HTTP routes of the registration portal
+func (s *signup) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveForm)
+	mux.HandleFunc("/signup", s.serveSignup)
+	return mux
+}
+
+func (s *signup) serveForm(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.render(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *signup) serveSignup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	fullName := strings.TrimSpace(r.PostFormValue("name"))
+	email := strings.TrimSpace(r.PostFormValue("email"))
+	data := map[string]interface{}{
+		"Name":  fullName,
+		"Email": email,
+	}
+
+	if s.accessCode != "" && subtle.ConstantTimeCompare([]byte(s.accessCode), []byte(r.PostFormValue("code"))) != 1 {
+		data["Error"] = "The access code is not valid"
+		s.render(w, http.StatusForbidden, data)
+		return
+	}
+	if fullName == "" {
+		data["Error"] = "Please enter your name"
+		s.render(w, http.StatusBadRequest, data)
+		return
+	}
+	if a, err := mail.ParseAddress(email); err != nil || a.Address != email {
+		data["Error"] = "Please enter a valid email address"
+		s.render(w, http.StatusBadRequest, data)
+		return
+	}
+
+	p, err := s.register(fullName, email)
+	if err != nil {
+		log.Errorf("Error registering %s, %v", email, err)
+		//the details of the error are for the workshop organizers only
+		data["Error"] = "Sorry, the registration failed, please try again or ask the workshop organizers for help"
+		s.render(w, http.StatusInternalServerError, data)
+		return
+	}
+	if p == nil {
+		data["Error"] = "Sorry, all the workshop seats are taken"
+		s.render(w, http.StatusConflict, data)
+		return
+	}
+
+	data["Participant"] = p
+	s.render(w, http.StatusOK, data)
+}
+
+//register provisions the next free participant slot for the attendee, nil is returned when all the slots are taken.
+//The slot is released when the provisioning fails, so that the attendee can register again.
+func (s *signup) register(fullName, email string) (*Participant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	giteaUsers := s.workshopOpts.GiteaUsers
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		p := giteaUsers.participant(i)
+		if _, resp, err := s.client.GetUserInfo(p.UserName); err == nil {
+			continue
+		} else if !isNotFound(resp) {
+			return nil, err
+		}
+
+		p.FullName = fullName
+		p.Email = email
+		if err := s.provision(p); err != nil {
+			s.release(p)
+			return nil, err
+		}
+		log.Infof("Registered %s <%s> as %s", fullName, email, p.UserName)
+		if err := s.workshopOpts.sendCredentials(p); err != nil {
+			log.Errorf("Error sending the credentials to %s, %v", p.Email, err)
+		}
+		return p, nil
+	}
+
+	log.Warnf("All the %d workshop seats are taken, refusing the registration of %s", giteaUsers.To-giteaUsers.From+1, email)
+	return nil, nil
+}
+
+//provision provisions the participant and stores its credentials
+func (s *signup) provision(p *Participant) error {
+	if err := s.workshopOpts.provisionParticipant(s.client, p, s.kubeconfig); err != nil {
+		return err
+	}
+	return s.workshopOpts.storeCredentials(p)
+}
+
+//release tears down the half provisioned participant, the Gitea user is only deleted when it was created
+//for the attendee i.e. it has the attendee email
+func (s *signup) release(p *Participant) {
+	u, err := giteaUser(s.client, p.UserName)
+	if err != nil {
+		log.Errorf("Error releasing the slot %s of %s, %v", p.UserName, p.Email, err)
+		return
+	}
+	if u == nil || !strings.EqualFold(u.Email, p.Email) {
+		return
+	}
+	if err := s.workshopOpts.teardownParticipant(s.client, p, s.kubeconfig); err != nil {
+		log.Errorf("Error releasing the slot %s of %s, %v", p.UserName, p.Email, err)
+		return
+	}
+	log.Infof("Released the slot %s of %s", p.UserName, p.Email)
+}
+
+func (s *signup) render(w http.ResponseWriter, status int, data map[string]interface{}) {
+	data["GiteaURL"] = s.workshopOpts.GiteaURL
+	data["DroneURL"] = s.workshopOpts.GiteaUsers.OAuthRedirectURI
+	data["AccessCode"] = s.accessCode != ""
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := signupTemplate.Execute(w, data); err != nil {
+		log.Errorf("Error rendering signup page %v", err)
+	}
+}
+
+var signupTemplate = template.Must(template.New("signup").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Workshop Registration</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em auto; max-width: 40em; }
+    label { display: block; margin-top: 1em; }
+    input { width: 100%; padding: 6px; }
+    .error { color: #b00020; }
+    dt { font-weight: bold; margin-top: 0.5em; }
+  </style>
+</head>
+<body>
+  <h1>Workshop Registration</h1>
+  {{- with .Participant }}
+  <p>Welcome {{ .FullName }}! Please note down your credentials, they are shown only once.</p>
+  <dl>
+    <dt>Gitea</dt><dd><a href="{{ $.GiteaURL }}">{{ $.GiteaURL }}</a></dd>
+    <dt>Username</dt><dd>{{ .UserName }}</dd>
+    <dt>Password</dt><dd>{{ .Password }}</dd>
+    {{- if $.DroneURL }}
+    <dt>Drone</dt><dd><a href="{{ $.DroneURL }}">{{ $.DroneURL }}</a></dd>
+    {{- end }}
+    {{- range .RepoCloneURLs }}
+    <dt>Repository</dt><dd>{{ . }}</dd>
+    {{- end }}
+  </dl>
+  {{- else }}
+  {{- with .Error }}<p class="error">{{ . }}</p>{{ end }}
+  <form method="post" action="/signup">
+    <label>Name <input name="name" value="{{ .Name }}" required></label>
+    <label>Email <input name="email" type="email" value="{{ .Email }}" required></label>
+    {{- if .AccessCode }}
+    <label>Access Code <input name="code" required></label>
+    {{- end }}
+    <p><button type="submit">Get my workshop account</button></p>
+  </form>
+  {{- end }}
+</body>
+</html>
+`))
@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+func TestSignup(t *testing.T) {
+	var created gitea.CreateUserOption
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/users/user-01": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"login":"user-01"}`)
+		},
+		"/api/v1/users/user-02": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"/api/v1/admin/users": func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Errorf("%v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"login":%q}`, created.Username)
+		},
+		"/api/v1/user/applications/oauth2": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id":1,"name":"demo-oauth-user-02"}`)
+				return
+			}
+			fmt.Fprint(w, `[]`)
+		},
+		"/api/v1/repos/user-02/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"/api/v1/repos/migrate": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"name":"jar-stack","clone_url":"%s/user-02/jar-stack.git"}`, "http://gitea")
+		},
+	})
+
+	workshopOpts, err := loadWorkshopOptions(writeWorkshopFile(t, s.URL))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	workshopOpts.CredentialsFile = filepath.Join(t.TempDir(), "credentials.yaml")
+	sp := &signup{
+		workshopOpts: workshopOpts,
+		client:       c,
+		accessCode:   "drone",
+	}
+
+	form := url.Values{"name": {"Jane Doe"}, "email": {"jane@example.com"}, "code": {"wrong"}}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	sp.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expecting registration with wrong access code to be forbidden but got %d", rec.Code)
+	}
+
+	form.Set("code", "drone")
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	sp.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expecting registration to succeed but got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if created.Username != "user-02" || created.FullName != "Jane Doe" || created.Email != "jane@example.com" {
+		t.Errorf("Expecting user-02 to be created for Jane Doe <jane@example.com> but got %v", created)
+	}
+	for _, expected := range []string{"user-02", "user-02@123", "http://gitea/user-02/jar-stack.git"} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("Expecting the credentials page to show %s", expected)
+		}
+	}
+	creds, err := loadCredentials(workshopOpts.CredentialsFile)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if p, ok := creds["user-02"]; !ok || p.Email != "jane@example.com" {
+		t.Errorf("Expecting the credentials of user-02 to be stored but got %v", creds)
+	}
+}
+
+func TestSignupReleasesSlot(t *testing.T) {
+	var email string
+	var deleted []string
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/users/user-01": func(w http.ResponseWriter, r *http.Request) {
+			if email == "" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `{"login":"user-01","email":%q}`, email)
+		},
+		"/api/v1/users/user-01/repos": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[]`)
+		},
+		"/api/v1/admin/users": func(w http.ResponseWriter, r *http.Request) {
+			var created gitea.CreateUserOption
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Errorf("%v", err)
+			}
+			email = created.Email
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"login":%q}`, created.Username)
+		},
+		"/api/v1/admin/users/user-01": func(w http.ResponseWriter, r *http.Request) {
+			deleted = append(deleted, "user-01")
+			email = ""
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"/api/v1/user/applications/oauth2": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id":1,"name":"demo-oauth-user-01"}`)
+				return
+			}
+			fmt.Fprint(w, `[]`)
+		},
+		"/api/v1/repos/user-01/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"/api/v1/repos/migrate": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message":"internal detail of the migration"}`)
+		},
+	})
+
+	workshopOpts, err := loadWorkshopOptions(writeWorkshopFile(t, s.URL))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	sp := &signup{
+		workshopOpts: workshopOpts,
+		client:       c,
+	}
+
+	form := url.Values{"name": {"Jane Doe"}, "email": {"jane@example.com"}}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	sp.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expecting the registration to fail but got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "internal detail") {
+		t.Errorf("Expecting the error details not to be shown to the attendee but got %s", rec.Body.String())
+	}
+	if len(deleted) != 1 || email != "" {
+		t.Errorf("Expecting the half provisioned user-01 to be deleted to release the slot but got %v", deleted)
+	}
+}
@@ -0,0 +1,355 @@
+package commands
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//SyncTemplateOptions the options to propagate the template repo changes to participant repos
+type SyncTemplateOptions struct {
+	configFile  string
+	sourceOwner string
+	branch      string
+	title       string
+}
+
+// SyncTemplateOptions implements Interface
+var _ Command = (*SyncTemplateOptions)(nil)
+
+var syncTemplateCommandExample = fmt.Sprintf(`
+  # Open pull requests with the template changes from the admin user's copy of the templates
+  %[1]s sync-template --workshop-file workshop.yaml
+  # Use the templates owned by the user or organization 'workshop'
+  %[1]s sync-template -f workshop.yaml --source-owner workshop
+`, ExamplePrefix())
+
+//NewSyncTemplateCommand instantiates the new instance of the SyncTemplateCommand
+func NewSyncTemplateCommand() *cobra.Command {
+	syncOpts := &SyncTemplateOptions{}
+
+	syncCmd := &cobra.Command{
+		Use:     "sync-template",
+		Short:   "Open pull requests on participant repos with the latest changes of the template repos",
+		Example: syncTemplateCommandExample,
+		RunE:    syncOpts.Execute,
+		PreRunE: syncOpts.Validate,
+	}
+
+	syncOpts.AddFlags(syncCmd)
+
+	return syncCmd
+}
+
+// AddFlags implements Command
+func (opts *SyncTemplateOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+	cmd.Flags().StringVarP(&opts.sourceOwner, "source-owner", "o", "", "The Gitea user or organization owning the up to date template repos, defaults to the Gitea admin user")
+	cmd.Flags().StringVarP(&opts.branch, "branch", "b", "", "The branch to commit the template changes to, defaults to template-sync-<short commit id of the template>")
+	cmd.Flags().StringVarP(&opts.title, "title", "t", "Sync with the workshop template", "The title of the pull request")
+}
+
+// Validate implements Command
+func (opts *SyncTemplateOptions) Validate(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// Execute implements Command
+func (opts *SyncTemplateOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	if opts.sourceOwner == "" {
+		opts.sourceOwner = workshopOpts.GiteaAdminUser
+	}
+
+	giteaUsers := workshopOpts.GiteaUsers
+	for _, repoURL := range giteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return err
+		}
+
+		//make sure we have the template in Gitea to diff against
+		if _, err := createRepo(c, repoURL, opts.sourceOwner, repoName); err != nil {
+			return err
+		}
+
+		for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+			if err := opts.syncRepo(workshopOpts, c, participantUserName(i), repoName); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//templateCommitTrailer is the trailer of the sync commits recording the template commit the repo was synced to
+const templateCommitTrailer = "Template-Commit: "
+
+//templateChange is a change of a template file applied to the participant repo
+type templateChange struct {
+	//Operation is the Gitea change file operation, create, update or delete
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	Content   string `json:"content,omitempty"`
+	//SHA is the blob SHA of the file in the participant repo, empty when the file is created
+	SHA string `json:"sha,omitempty"`
+}
+
+//changeFilesOption is the option to commit the changes of many files at once, missing in the Gitea SDK
+type changeFilesOption struct {
+	Branch    string           `json:"branch"`
+	NewBranch string           `json:"new_branch"`
+	Message   string           `json:"message"`
+	Files     []templateChange `json:"files"`
+}
+
+//syncRepo commits the changes of the template since the template commit the user's repo was created from or last
+//synced to on to a new branch of the user's repo and opens a pull request to merge it in to the default branch.
+//Only the files the template changed are touched, those the user changed too are reported as conflicts.
+func (opts *SyncTemplateOptions) syncRepo(workshopOpts *WorkshopOptions, c *gitea.Client, user, repoName string) error {
+	src, _, err := c.GetRepo(opts.sourceOwner, repoName)
+	if err != nil {
+		return err
+	}
+	srcBranch, _, err := c.GetRepoBranch(opts.sourceOwner, repoName, src.DefaultBranch)
+	if err != nil {
+		return err
+	}
+	head := srcBranch.Commit.ID
+
+	dst, resp, err := c.GetRepo(user, repoName)
+	if err != nil {
+		if isNotFound(resp) {
+			log.Warnf("Repo %s does not exist for user %s, skipping sync", repoName, user)
+			return nil
+		}
+		return err
+	}
+
+	branch := opts.branch
+	if branch == "" {
+		branch = fmt.Sprintf("template-sync-%.7s", head)
+	}
+
+	if _, resp, err := c.GetRepoBranch(user, repoName, branch); err == nil {
+		log.Infof("Branch %s already exists in %s/%s, skipping sync", branch, user, repoName)
+		return nil
+	} else if !isNotFound(resp) {
+		return err
+	}
+
+	base, err := opts.templateBase(c, user, repoName, dst.DefaultBranch)
+	if err != nil {
+		return err
+	}
+	if base == "" {
+		log.Warnf("No commit of the template %s/%s found in %s/%s, skipping sync", opts.sourceOwner, repoName, user, repoName)
+		return nil
+	}
+	if base == head {
+		log.Infof("Repo %s/%s is up to date with the template", user, repoName)
+		return nil
+	}
+
+	baseTree, err := gitTree(workshopOpts, opts.sourceOwner, repoName, base)
+	if err != nil {
+		return err
+	}
+	headTree, err := gitTree(workshopOpts, opts.sourceOwner, repoName, head)
+	if err != nil {
+		return err
+	}
+	dstTree, err := gitTree(workshopOpts, user, repoName, dst.DefaultBranch)
+	if err != nil {
+		return err
+	}
+
+	changes, conflicts := templateChanges(baseTree, headTree, dstTree)
+	if len(changes) == 0 {
+		if len(conflicts) > 0 {
+			log.Warnf("Repo %s/%s changed all the files the template changed, merge them by hand:\n  %s",
+				user, repoName, strings.Join(conflicts, "\n  "))
+			return nil
+		}
+		log.Infof("Repo %s/%s is up to date with the template", user, repoName)
+		return nil
+	}
+
+	var paths []string
+	for i, change := range changes {
+		paths = append(paths, change.Path)
+		if change.Operation == "delete" {
+			continue
+		}
+		b, _, err := c.GetFile(opts.sourceOwner, repoName, head, change.Path)
+		if err != nil {
+			return err
+		}
+		changes[i].Content = base64.StdEncoding.EncodeToString(b)
+	}
+
+	//all the template changes in one commit
+	if err := giteaRequest(workshopOpts.GiteaURL, workshopOpts.GiteaAdminUser, workshopOpts.GiteaAdminPassword, "",
+		http.MethodPost, fmt.Sprintf("/repos/%s/%s/contents", user, repoName), changeFilesOption{
+			Branch:    dst.DefaultBranch,
+			NewBranch: branch,
+			Message:   fmt.Sprintf("Sync with the workshop template %.7s\n\n%s%s", head, templateCommitTrailer, head),
+			Files:     changes,
+		}, nil); err != nil {
+		return fmt.Errorf("error committing the template changes to %s/%s, changing many files at once requires Gitea 1.20 or later, %v", user, repoName, err)
+	}
+
+	body := fmt.Sprintf("The workshop template %s/%s was updated to %.7s.\n\nChanged files:\n\n- %s\n",
+		opts.sourceOwner, repoName, head, strings.Join(paths, "\n- "))
+	if len(conflicts) > 0 {
+		body += fmt.Sprintf("\nConflicts, these files were changed both in the template and in your repo and are left as they are, merge the template changes by hand:\n\n- %s\n",
+			strings.Join(conflicts, "\n- "))
+	}
+
+	pr, _, err := c.CreatePullRequest(user, repoName, gitea.CreatePullRequestOption{
+		Head:  branch,
+		Base:  dst.DefaultBranch,
+		Title: opts.title,
+		Body:  body,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Opened pull request %s with %d template changes and %d conflicts", pr.HTMLURL, len(paths), len(conflicts))
+
+	return nil
+}
+
+//templateBase returns the template commit the user's repo was last synced to, recorded by the trailer of the
+//sync commit, or created from, the latest commit of the repo that is in the template, empty when there is none
+func (opts *SyncTemplateOptions) templateBase(c *gitea.Client, user, repoName, ref string) (string, error) {
+	for page := 1; ; page++ {
+		commits, _, err := c.ListRepoCommits(user, repoName, gitea.ListCommitOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: 50},
+			SHA:         ref,
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, commit := range commits {
+			if commit.RepoCommit != nil {
+				for _, line := range strings.Split(commit.RepoCommit.Message, "\n") {
+					if strings.HasPrefix(line, templateCommitTrailer) {
+						return strings.TrimSpace(strings.TrimPrefix(line, templateCommitTrailer)), nil
+					}
+				}
+			}
+			if _, resp, err := c.GetSingleCommit(opts.sourceOwner, repoName, commit.SHA); err == nil {
+				return commit.SHA, nil
+			} else if !isNotFound(resp) {
+				return "", err
+			}
+		}
+		if len(commits) < 50 {
+			return "", nil
+		}
+	}
+}
+
+//templateChanges returns the changes of the template files between the base and the head template trees,
+//to apply to the participant repo, and the paths the participant changed too, left as they are as conflicts.
+//The files the template did not change are left as is whether or not the participant changed them.
+func templateChanges(base, head, participant []gitea.GitEntry) ([]templateChange, []string) {
+	baseBlobs, headBlobs, participantBlobs := blobs(base), blobs(head), blobs(participant)
+
+	paths := make(map[string]bool)
+	for p := range baseBlobs {
+		paths[p] = true
+	}
+	for p := range headBlobs {
+		paths[p] = true
+	}
+
+	var changes []templateChange
+	var conflicts []string
+	for p := range paths {
+		baseSHA, inBase := baseBlobs[p]
+		headSHA, inHead := headBlobs[p]
+		participantSHA, inParticipant := participantBlobs[p]
+		//unchanged by the template or already the same as the template
+		if (inBase == inHead && baseSHA == headSHA) || (inHead == inParticipant && headSHA == participantSHA) {
+			continue
+		}
+		//changed by the participant
+		if inBase != inParticipant || baseSHA != participantSHA {
+			conflicts = append(conflicts, p)
+			continue
+		}
+		switch {
+		case !inHead:
+			changes = append(changes, templateChange{Operation: "delete", Path: p, SHA: participantSHA})
+		case !inParticipant:
+			changes = append(changes, templateChange{Operation: "create", Path: p})
+		default:
+			changes = append(changes, templateChange{Operation: "update", Path: p, SHA: participantSHA})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	sort.Strings(conflicts)
+
+	return changes, conflicts
+}
+
+//blobs returns the blob SHAs of the files of the tree by path, skipping directories, submodules and symlinks
+func blobs(entries []gitea.GitEntry) map[string]string {
+	blobs := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if e.Type == "blob" && e.Mode != "120000" {
+			blobs[e.Path] = e.SHA
+		}
+	}
+	return blobs
+}
+
+//gitTreePageSize is the number of entries of a page of a git tree, the maximum of Gitea
+const gitTreePageSize = 1000
+
+//gitTree returns all the entries of the recursive git tree of the ref, paging through the truncated tree
+//as a partial tree would sync the missing entries as deleted or created files
+func gitTree(workshopOpts *WorkshopOptions, owner, repoName, ref string) ([]gitea.GitEntry, error) {
+	var entries []gitea.GitEntry
+	for page := 1; ; page++ {
+		var tree gitea.GitTreeResponse
+		err := giteaRequest(workshopOpts.GiteaURL, workshopOpts.GiteaAdminUser, workshopOpts.GiteaAdminPassword, "", http.MethodGet,
+			fmt.Sprintf("/repos/%s/%s/git/trees/%s?recursive=1&page=%d&per_page=%d", owner, repoName, url.PathEscape(ref), page, gitTreePageSize), nil, &tree)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, tree.Entries...)
+		if !tree.Truncated {
+			return entries, nil
+		}
+		if len(tree.Entries) == 0 {
+			return nil, fmt.Errorf("the tree %s of repo %s/%s is truncated", ref, owner, repoName)
+		}
+	}
+}
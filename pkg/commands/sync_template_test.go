@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+func TestTemplateChanges(t *testing.T) {
+	base := []gitea.GitEntry{
+		{Path: "README.md", Type: "blob", Mode: "100644", SHA: "a1"},
+		{Path: "src", Type: "tree", Mode: "040000", SHA: "t1"},
+		{Path: "src/main.go", Type: "blob", Mode: "100644", SHA: "b1"},
+		{Path: "pom.xml", Type: "blob", Mode: "100644", SHA: "c1"},
+		{Path: "old.txt", Type: "blob", Mode: "100644", SHA: "d1"},
+		{Path: "Dockerfile", Type: "blob", Mode: "100644", SHA: "e1"},
+	}
+	head := []gitea.GitEntry{
+		{Path: "README.md", Type: "blob", Mode: "100644", SHA: "a1"},
+		{Path: "src", Type: "tree", Mode: "040000", SHA: "t2"},
+		{Path: "src/main.go", Type: "blob", Mode: "100644", SHA: "b2"},
+		{Path: "pom.xml", Type: "blob", Mode: "100644", SHA: "c2"},
+		{Path: ".drone.yml", Type: "blob", Mode: "100644", SHA: "f2"},
+		{Path: "Dockerfile", Type: "blob", Mode: "100644", SHA: "e2"},
+		{Path: "link", Type: "blob", Mode: "120000", SHA: "g2"},
+	}
+	participant := []gitea.GitEntry{
+		//edited by the participant, unchanged by the template
+		{Path: "README.md", Type: "blob", Mode: "100644", SHA: "a9"},
+		{Path: "src", Type: "tree", Mode: "040000", SHA: "t1"},
+		{Path: "src/main.go", Type: "blob", Mode: "100644", SHA: "b1"},
+		//edited by the participant and by the template
+		{Path: "pom.xml", Type: "blob", Mode: "100644", SHA: "c9"},
+		{Path: "old.txt", Type: "blob", Mode: "100644", SHA: "d1"},
+		//already the same as the template
+		{Path: "Dockerfile", Type: "blob", Mode: "100644", SHA: "e2"},
+		{Path: "notes.txt", Type: "blob", Mode: "100644", SHA: "h1"},
+	}
+
+	expected := []templateChange{
+		{Operation: "create", Path: ".drone.yml"},
+		{Operation: "delete", Path: "old.txt", SHA: "d1"},
+		{Operation: "update", Path: "src/main.go", SHA: "b1"},
+	}
+	changes, conflicts := templateChanges(base, head, participant)
+	if !reflect.DeepEqual(expected, changes) {
+		t.Errorf("Expecting changes %v but got %v", expected, changes)
+	}
+	if expected := []string{"pom.xml"}; !reflect.DeepEqual(expected, conflicts) {
+		t.Errorf("Expecting conflicts %v but got %v", expected, conflicts)
+	}
+}
+
+func TestTemplateChangesParticipantEdits(t *testing.T) {
+	template := []gitea.GitEntry{
+		{Path: "README.md", Type: "blob", Mode: "100644", SHA: "a1"},
+		{Path: "src/main.go", Type: "blob", Mode: "100644", SHA: "b1"},
+	}
+	participant := []gitea.GitEntry{
+		{Path: "README.md", Type: "blob", Mode: "100644", SHA: "a9"},
+	}
+	if changes, conflicts := templateChanges(template, template, participant); len(changes) != 0 || len(conflicts) != 0 {
+		t.Errorf("Expecting the participant edits of the files the template did not change to be kept but got %v and conflicts %v", changes, conflicts)
+	}
+}
+
+func TestSyncTemplate(t *testing.T) {
+	trees := map[string]string{
+		"/api/v1/repos/demo/jar-stack/git/trees/base0001":   `{"sha":"base0001","tree":[{"path":"README.md","type":"blob","mode":"100644","sha":"a1"},{"path":"pom.xml","type":"blob","mode":"100644","sha":"c1"},{"path":"Dockerfile","type":"blob","mode":"100644","sha":"e1"}]}`,
+		"/api/v1/repos/demo/jar-stack/git/trees/head0002":   `{"sha":"head0002","tree":[{"path":"README.md","type":"blob","mode":"100644","sha":"a1"},{"path":"pom.xml","type":"blob","mode":"100644","sha":"c2"},{"path":"Dockerfile","type":"blob","mode":"100644","sha":"e2"}]}`,
+		"/api/v1/repos/user-01/jar-stack/git/trees/main":    `{"sha":"p1","tree":[{"path":"README.md","type":"blob","mode":"100644","sha":"a9"},{"path":"pom.xml","type":"blob","mode":"100644","sha":"c9"},{"path":"Dockerfile","type":"blob","mode":"100644","sha":"e1"}]}`,
+		"/api/v1/repos/demo/jar-stack/git/commits/base0001": `{"sha":"base0001"}`,
+	}
+	var commit changeFilesOption
+	var pr gitea.CreatePullRequestOption
+	handlers := map[string]http.HandlerFunc{
+		"/api/v1/repos/demo/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","default_branch":"main"}`)
+		},
+		"/api/v1/repos/demo/jar-stack/branches/main": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"main","commit":{"id":"head0002"}}`)
+		},
+		"/api/v1/repos/demo/jar-stack/raw/Dockerfile": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "FROM openjdk:11")
+		},
+		"/api/v1/repos/user-01/jar-stack": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"name":"jar-stack","default_branch":"main"}`)
+		},
+		"/api/v1/repos/user-01/jar-stack/branches/template-sync-head000": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		},
+		"/api/v1/repos/user-01/jar-stack/commits": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"sha":"p1","commit":{"message":"my own change"}},{"sha":"base0001","commit":{"message":"initial"}}]`)
+		},
+		"/api/v1/repos/demo/jar-stack/git/commits/p1": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		},
+		"/api/v1/repos/user-01/jar-stack/contents": func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&commit); err != nil {
+				t.Errorf("%v", err)
+			}
+			fmt.Fprint(w, `{}`)
+		},
+		"/api/v1/repos/user-01/jar-stack/pulls": func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+				t.Errorf("%v", err)
+			}
+			fmt.Fprint(w, `{"html_url":"http://gitea/user-01/jar-stack/pulls/1"}`)
+		},
+	}
+	for p, tree := range trees {
+		tree := tree
+		handlers[p] = func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, tree)
+		}
+	}
+	s := newFakeGitea(t, handlers)
+	workshopFile := writeWorkshopFile(t, s.URL, func(o *WorkshopOptions) {
+		o.GiteaUsers.To = 1
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"sync-template", "-f", workshopFile})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	//the README.md edited by the participant is left as is and pom.xml is a conflict
+	expected := []templateChange{{Operation: "update", Path: "Dockerfile", SHA: "e1", Content: "RlJPTSBvcGVuamRrOjEx"}}
+	if !reflect.DeepEqual(expected, commit.Files) {
+		t.Errorf("Expecting the single commit of the changes %v but got %v", expected, commit.Files)
+	}
+	if commit.NewBranch != "template-sync-head000" || !strings.Contains(commit.Message, templateCommitTrailer+"head0002") {
+		t.Errorf("Expecting the commit on a new branch recording the template commit but got %v", commit)
+	}
+	if !strings.Contains(pr.Body, "- Dockerfile") || !strings.Contains(pr.Body, "Conflicts") || !strings.Contains(pr.Body, "- pom.xml") {
+		t.Errorf("Expecting the pull request to list the changes and the conflicts but got %s", pr.Body)
+	}
+}
+
+func TestTemplateBaseTrailer(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/repos/user-01/jar-stack/commits": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `[{"sha":"p2","commit":{"message":"Merge the sync"}},{"sha":"s1","commit":{"message":"Sync with the workshop template head000\n\n%shead0002"}}]`, templateCommitTrailer)
+		},
+		"/api/v1/repos/demo/jar-stack/git/commits/p2": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		},
+	})
+	workshopOpts := &WorkshopOptions{GiteaURL: s.URL, GiteaAdminUser: "demo", GiteaAdminPassword: "demo@123"}
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	opts := &SyncTemplateOptions{sourceOwner: "demo"}
+	if base, err := opts.templateBase(c, "user-01", "jar-stack", "main"); err != nil || base != "head0002" {
+		t.Errorf("Expecting the template commit of the last sync head0002 but got %q, %v", base, err)
+	}
+}
+
+func TestGitTreePages(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/repos/demo/jar-stack/git/trees/main": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("recursive") != "1" {
+				t.Errorf("Expecting the recursive tree but got %s", r.URL.RawQuery)
+			}
+			switch r.URL.Query().Get("page") {
+			case "1":
+				fmt.Fprint(w, `{"sha":"main","truncated":true,"page":1,"tree":[{"path":"README.md","type":"blob","mode":"100644","sha":"a1"}]}`)
+			case "2":
+				fmt.Fprint(w, `{"sha":"main","truncated":false,"page":2,"tree":[{"path":"pom.xml","type":"blob","mode":"100644","sha":"c1"}]}`)
+			default:
+				t.Errorf("Unexpected page %s", r.URL.Query().Get("page"))
+			}
+		},
+	})
+	workshopOpts := &WorkshopOptions{GiteaURL: s.URL, GiteaAdminUser: "demo", GiteaAdminPassword: "demo@123"}
+	entries, err := gitTree(workshopOpts, "demo", "jar-stack", "main")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := []gitea.GitEntry{
+		{Path: "README.md", Type: "blob", Mode: "100644", SHA: "a1"},
+		{Path: "pom.xml", Type: "blob", Mode: "100644", SHA: "c1"},
+	}
+	if !reflect.DeepEqual(expected, entries) {
+		t.Errorf("Expecting the entries of all the pages %v but got %v", expected, entries)
+	}
+}
@@ -1,25 +1,77 @@
 package commands
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"code.gitea.io/sdk/gitea"
 )
 
+//giteaTimeout is how long a request to the Gitea API may take, migrating a template repo is synchronous
+const giteaTimeout = 2 * time.Minute
+
+//giteaHTTPClient is the HTTP client of the Gitea SDK clients and of the requests the SDK has no method for
+var giteaHTTPClient = &http.Client{Timeout: giteaTimeout}
+
 //newGiteaClient creates new Gitea Client
 func (opts *WorkshopOptions) newGiteaClient() (*gitea.Client, error) {
-	c, err := gitea.NewClient(opts.GiteaURL)
-	c.SetBasicAuth(opts.GiteaAdminUser, opts.GiteaAdminPassword)
+	c, err := gitea.NewClient(opts.GiteaURL,
+		gitea.SetBasicAuth(opts.GiteaAdminUser, opts.GiteaAdminPassword),
+		gitea.SetHTTPClient(giteaHTTPClient))
 	if err != nil {
 		return nil, err
 	}
 	return c, nil
 }
 
+//giteaRequest sends the JSON request the Gitea SDK has no method for to the Gitea API path, authenticated as
+//the user and on behalf of the sudo user when set, and decodes the response in to out when it is not nil
+func giteaRequest(giteaURL, user, password, sudo, method, apiPath string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/api/v1%s", strings.TrimSuffix(giteaURL, "/"), apiPath), body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(user, password)
+	req.Header.Set("Content-Type", "application/json")
+	if sudo != "" {
+		req.Header.Set("Sudo", sudo)
+	}
+
+	resp, err := giteaHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
 //randomHex generates and returns a random 16 digit Hex value
 func randomHex(n int) (string, error) {
 	bytes := make([]byte, n)
@@ -44,3 +96,13 @@ func repoNameFromURL(repoUrl string) (string, error) {
 
 	return repoName, nil
 }
+
+//participantUserName returns the Gitea username of the i-th workshop participant e.g. user-01
+func participantUserName(i int) string {
+	return fmt.Sprintf("user-%02d", i)
+}
+
+//isNotFound checks if the Gitea API response is a 404 Not Found
+func isNotFound(resp *gitea.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
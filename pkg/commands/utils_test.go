@@ -1,6 +1,13 @@
 package commands
 
-import "testing"
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestRepoNameFromURL(t *testing.T) {
 	repoURL := "https://github.com/kameshsampath/jar-stack"
@@ -23,3 +30,39 @@ func TestRepoNameFromURLWithExt(t *testing.T) {
 		t.Errorf("Expecting 'jar-stack' but got %s", repoName)
 	}
 }
+
+func TestGiteaRequest(t *testing.T) {
+	s := newFakeGitea(t, map[string]http.HandlerFunc{
+		"/api/v1/user/applications/oauth2": func(w http.ResponseWriter, r *http.Request) {
+			if user, password, _ := r.BasicAuth(); user != "demo" || password != "demo@123" || r.Header.Get("Sudo") != "user-01" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var in map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				t.Errorf("%v", err)
+			}
+			fmt.Fprintf(w, `{"name":%q}`, in["name"])
+		},
+		"/api/v1/slow": func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		},
+	})
+
+	var out struct {
+		Name string `json:"name"`
+	}
+	if err := giteaRequest(s.URL, "demo", "demo@123", "user-01", http.MethodPost, "/user/applications/oauth2", map[string]string{"name": "drone"}, &out); err != nil || out.Name != "drone" {
+		t.Errorf("Expecting the oAuth application drone but got %v, %v", out, err)
+	}
+	if err := giteaRequest(s.URL, "demo", "wrong", "", http.MethodGet, "/user/applications/oauth2", nil, nil); err == nil || !strings.HasPrefix(err.Error(), "401") {
+		t.Errorf("Expecting the unauthorized request to fail but got %v", err)
+	}
+
+	timeout := giteaHTTPClient.Timeout
+	giteaHTTPClient.Timeout = 50 * time.Millisecond
+	defer func() { giteaHTTPClient.Timeout = timeout }()
+	if err := giteaRequest(s.URL, "demo", "demo@123", "", http.MethodGet, "/slow", nil, nil); err == nil {
+		t.Errorf("Expecting the hung request to time out")
+	}
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+//ValidatePipelinesOptions the options to validate the Drone pipelines of the template repos
+type ValidatePipelinesOptions struct {
+	configFile string
+}
+
+// ValidatePipelinesOptions implements Interface
+var _ Command = (*ValidatePipelinesOptions)(nil)
+
+var validatePipelinesCommandExample = fmt.Sprintf(`
+  # Validate the .drone.yml of the template repos, as setup-workshop does before provisioning
+  %[1]s validate-pipelines --workshop-file workshop.yaml
+`, ExamplePrefix())
+
+//NewValidatePipelinesCommand instantiates the new instance of the ValidatePipelinesCommand
+func NewValidatePipelinesCommand() *cobra.Command {
+	validateOpts := &ValidatePipelinesOptions{}
+
+	validateCmd := &cobra.Command{
+		Use:     "validate-pipelines",
+		Short:   "Validate the Drone pipelines of the template repos",
+		Example: validatePipelinesCommandExample,
+		RunE:    validateOpts.Execute,
+		PreRunE: validateOpts.Validate,
+	}
+
+	validateOpts.AddFlags(validateCmd)
+
+	return validateCmd
+}
+
+// AddFlags implements Command
+func (opts *ValidatePipelinesOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&opts.configFile, "workshop-file", "f", "", "The workshop configuration file")
+	if err := cmd.MarkFlagRequired("workshop-file"); err != nil {
+		log.Fatalf("Error marking flag 'workshop-file' as required %v", err)
+	}
+}
+
+// Validate implements Command
+func (opts *ValidatePipelinesOptions) Validate(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// Execute implements Command
+func (opts *ValidatePipelinesOptions) Execute(cmd *cobra.Command, args []string) error {
+	workshopOpts, err := loadWorkshopOptions(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	c, err := workshopOpts.newGiteaClient()
+	if err != nil {
+		return err
+	}
+
+	if err := workshopOpts.validateDronePipelines(c); err != nil {
+		return err
+	}
+
+	log.Infof("The Drone pipelines of the templates of %s are valid", opts.configFile)
+
+	return nil
+}
@@ -0,0 +1,457 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"code.gitea.io/sdk/gitea"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+//reconcileParticipants provisions the missing participants, heals the drift of the existing ones and tears down
+//the recorded participants no longer in the workshop. A failing participant does not stop the others from being
+//reconciled. The existing Gitea users the workshop did not provision, e.g. of another workshop, are left as they are
+func (opts *WorkshopOptions) reconcileParticipants(kubeconfig string, recorded []WorkshopParticipantStatus) ([]WorkshopParticipantStatus, error) {
+	giteaUsers := opts.GiteaUsers
+
+	c, err := opts.newGiteaClient()
+	if err != nil {
+		return recorded, err
+	}
+
+	if giteaUsers.SharedOAuthApps {
+		if err := opts.createSharedOAuthApps(c, kubeconfig); err != nil {
+			return recorded, err
+		}
+	}
+
+	provisioned := make(map[string]bool)
+	for _, s := range recorded {
+		provisioned[s.UserName] = s.Provisioned
+	}
+
+	var statuses []WorkshopParticipantStatus
+	failed := 0
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		p := giteaUsers.participant(i)
+		status := WorkshopParticipantStatus{UserName: p.UserName, Namespace: p.Namespace, Ready: true, Provisioned: true}
+		//the participants are claimed before their Gitea user is created
+		if !provisioned[p.UserName] {
+			status.Ready, status.Provisioned = false, false
+			status.Message = "the participant is not claimed by the workshop yet"
+			if u, err := giteaUser(c, p.UserName); err != nil {
+				status.Message = err.Error()
+			} else if u != nil {
+				status.Message = "the Gitea user exists and was not provisioned by the workshop"
+			}
+			statuses = append(statuses, status)
+			failed++
+			continue
+		}
+		if err := opts.reconcileParticipant(c, p, kubeconfig); err != nil {
+			log.Errorf("Error reconciling participant %s, %v", p.UserName, err)
+			status.Ready, status.Message = false, err.Error()
+			failed++
+		}
+		status.Repos = p.RepoCloneURLs
+		statuses = append(statuses, status)
+		delete(provisioned, p.UserName)
+	}
+
+	//the participants removed from the workshop e.g. by lowering to
+	for _, s := range recorded {
+		if !provisioned[s.UserName] {
+			continue
+		}
+		if err := opts.teardownRecordedParticipant(c, s, kubeconfig); err != nil {
+			log.Errorf("Error tearing down participant %s, %v", s.UserName, err)
+			s.Ready, s.Message = false, fmt.Sprintf("error tearing down the participant removed from the workshop, %v", err)
+			statuses = append(statuses, s)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return statuses, fmt.Errorf("%d of %d participants are not ready", failed, len(statuses))
+	}
+	return statuses, nil
+}
+
+//claimParticipants returns the recorded participants along with the participants whose Gitea user does not exist,
+//recorded as provisioned by the workshop. The claims are recorded before the Gitea users are created, so that
+//the users created by the workshop are owned by it even when the status update after their creation is lost
+func (opts *WorkshopOptions) claimParticipants(recorded []WorkshopParticipantStatus) ([]WorkshopParticipantStatus, error) {
+	giteaUsers := opts.GiteaUsers
+
+	c, err := opts.newGiteaClient()
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := append([]WorkshopParticipantStatus(nil), recorded...)
+	index := make(map[string]int)
+	for i, s := range claimed {
+		index[s.UserName] = i
+	}
+	for i := giteaUsers.From; i <= giteaUsers.To; i++ {
+		p := giteaUsers.participant(i)
+		r, ok := index[p.UserName]
+		if ok && claimed[r].Provisioned {
+			continue
+		}
+		u, err := giteaUser(c, p.UserName)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			continue
+		}
+		claim := WorkshopParticipantStatus{UserName: p.UserName, Namespace: p.Namespace, Provisioned: true, Message: "the participant is being provisioned"}
+		if ok {
+			claimed[r] = claim
+		} else {
+			claimed = append(claimed, claim)
+		}
+	}
+	return claimed, nil
+}
+
+//giteaUser returns the Gitea user, nil when it does not exist
+func giteaUser(c *gitea.Client, userName string) (*gitea.User, error) {
+	u, resp, err := c.GetUserInfo(userName)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if u == nil || u.UserName == "" {
+		return nil, nil
+	}
+	return u, nil
+}
+
+//reconcileParticipant provisions the participant when the Gitea user does not exist, otherwise recreates
+//what is missing of its namespace, oAuth applications, Drone server, repos and credential secrets
+func (opts *WorkshopOptions) reconcileParticipant(c *gitea.Client, p *Participant, kubeconfig string) error {
+	giteaUsers := opts.GiteaUsers
+
+	//the secrets hold the rotated credentials
+	if err := opts.storedCredentials(p); err != nil {
+		return err
+	}
+
+	u, err := giteaUser(c, p.UserName)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		if err := opts.provisionParticipant(c, p, kubeconfig); err != nil {
+			return err
+		}
+		if err := opts.storeCredentials(p); err != nil {
+			return err
+		}
+		if err := opts.sendCredentials(p); err != nil {
+			log.Errorf("Error sending the credentials to %s, resend them using send-credentials, %v", p.Email, err)
+		}
+		return nil
+	}
+
+	c.SetSudo(p.UserName)
+	defer c.SetSudo(opts.GiteaAdminUser)
+
+	if err := opts.applyParticipantNamespace(p, kubeconfig); err != nil {
+		return err
+	}
+	//a run that failed after creating the user did not store its kubeconfig
+	if p.Kubeconfig == "" && opts.CredentialsFile != "" {
+		if p.Kubeconfig, err = opts.participantKubeconfig(p, kubeconfig); err != nil {
+			return err
+		}
+		if p.Kubeconfig != "" {
+			if err := opts.storeCredentials(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !giteaUsers.SharedOAuthApps {
+		oAuthApps, err := opts.participantOAuthApps(p, kubeconfig)
+		if err != nil {
+			return err
+		}
+		for _, oauthOpts := range oAuthApps {
+			if err := oauthOpts.ensureOAuthApp(c); err != nil {
+				return err
+			}
+		}
+		if len(oAuthApps) > 0 {
+			if err := opts.applyDroneServer(p, oAuthApps[0], kubeconfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, repoURL := range giteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return err
+		}
+		repo, err := createRepo(c, repoURL, p.UserName, repoName)
+		if err != nil {
+			return err
+		}
+		p.RepoCloneURLs = append(p.RepoCloneURLs, repo.CloneURL)
+	}
+
+	return opts.applyCredentialSecrets(p, kubeconfig)
+}
+
+//ensureOAuthApp creates the missing oAuth application, an existing one is only updated when its
+//Kubernetes secret is missing as updating it regenerates the client secret
+func (opts *OAuthAppOptions) ensureOAuthApp(c *gitea.Client) error {
+	o, err := findOAuthApp(c, opts.oAuthAppName)
+	if err != nil {
+		return err
+	}
+	if o != nil {
+		if !opts.addKubernetesSecret {
+			return nil
+		}
+		clientset, err := newKubernetesClient(opts.kubeconfig)
+		if err != nil {
+			return err
+		}
+		namespace := opts.namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		_, err = clientset.CoreV1().Secrets(namespace).Get(context.TODO(), opts.kubernetesSecretName(), metav1.GetOptions{})
+		if err == nil || !apierrors.IsNotFound(err) {
+			return err
+		}
+		log.Infof("Kubernetes secret %s/%s of oAuth application %s is missing, regenerating the client secret", namespace, opts.kubernetesSecretName(), o.Name)
+	}
+	_, err = opts.createOAuthApp(c)
+	return err
+}
+
+//teardown deletes the recorded participants the workshop provisioned with their repos, namespaces and secrets,
+//and the shared oAuth applications, it returns the participants that could not be torn down
+func (opts *WorkshopOptions) teardown(kubeconfig string, recorded []WorkshopParticipantStatus) ([]WorkshopParticipantStatus, error) {
+	giteaUsers := opts.GiteaUsers
+
+	c, err := opts.newGiteaClient()
+	if err != nil {
+		return recorded, err
+	}
+
+	var remaining []WorkshopParticipantStatus
+	for _, s := range recorded {
+		if !s.Provisioned {
+			continue
+		}
+		if err := opts.teardownRecordedParticipant(c, s, kubeconfig); err != nil {
+			log.Errorf("Error tearing down participant %s, %v", s.UserName, err)
+			s.Ready, s.Message = false, fmt.Sprintf("error tearing down the participant, %v", err)
+			remaining = append(remaining, s)
+		}
+	}
+	if len(remaining) > 0 {
+		return remaining, fmt.Errorf("%d participants could not be torn down", len(remaining))
+	}
+
+	if giteaUsers.SharedOAuthApps {
+		oAuthApps, err := opts.participantOAuthApps(nil, kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, oauthOpts := range oAuthApps {
+			o, err := findOAuthApp(c, oauthOpts.oAuthAppName)
+			if err != nil {
+				return nil, err
+			}
+			if o != nil {
+				if _, err := c.DeleteOauth2(o.ID); err != nil {
+					return nil, err
+				}
+				log.Infof("Deleted shared oAuth application %s", o.Name)
+			}
+			if oauthOpts.addKubernetesSecret {
+				if err := oauthOpts.deleteKubernetesSecret(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+//teardownRecordedParticipant tears down the participant recorded in the status of the workshop,
+//in the namespace it was provisioned in
+func (opts *WorkshopOptions) teardownRecordedParticipant(c *gitea.Client, s WorkshopParticipantStatus, kubeconfig string) error {
+	var i int
+	if _, err := fmt.Sscanf(s.UserName, "user-%d", &i); err != nil {
+		return fmt.Errorf("invalid participant %q, %v", s.UserName, err)
+	}
+	p := opts.GiteaUsers.participant(i)
+	p.Namespace = s.Namespace
+	return opts.teardownParticipant(c, p, kubeconfig)
+}
+
+//teardownParticipant deletes the repos and the Gitea user of the participant, its oAuth application
+//and credential secrets and its namespace
+func (opts *WorkshopOptions) teardownParticipant(c *gitea.Client, p *Participant, kubeconfig string) error {
+	giteaUsers := opts.GiteaUsers
+
+	var clientset kubernetes.Interface
+	if giteaUsers.ParticipantNamespaces != nil || giteaUsers.ArgoCDRepoSecrets != nil || giteaUsers.TektonGitSecrets != nil {
+		var err error
+		if clientset, err = newKubernetesClient(kubeconfig); err != nil {
+			return err
+		}
+	}
+
+	for _, repoURL := range giteaUsers.Repos {
+		repoName, err := repoNameFromURL(repoURL)
+		if err != nil {
+			return err
+		}
+		if resp, err := c.DeleteRepo(p.UserName, repoName); err != nil && !isNotFound(resp) {
+			return err
+		}
+		if giteaUsers.ArgoCDRepoSecrets != nil {
+			namespace, err := giteaUsers.ArgoCDRepoSecrets.namespace(p)
+			if err != nil {
+				return err
+			}
+			secret, err := argoCDRepoSecret(p, repoURL)
+			if err != nil {
+				return err
+			}
+			if err := deleteSecret(clientset, namespace, secret.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	//Gitea refuses to delete a user owning repos e.g. the forks or the repos the participant created
+	if err := deleteUserRepos(c, p.UserName); err != nil {
+		return err
+	}
+	if resp, err := c.AdminDeleteUser(p.UserName); err != nil && !isNotFound(resp) {
+		return err
+	}
+	log.Infof("Deleted user %s", p.UserName)
+
+	if giteaUsers.AddKubernetesSecret && !giteaUsers.SharedOAuthApps {
+		oAuthApps, err := opts.participantOAuthApps(p, kubeconfig)
+		if err != nil {
+			return err
+		}
+		for _, oauthOpts := range oAuthApps {
+			if err := oauthOpts.deleteKubernetesSecret(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if tk := giteaUsers.TektonGitSecrets; tk != nil {
+		namespace, _, err := tk.serviceAccount(p)
+		if err != nil {
+			return err
+		}
+		if err := deleteSecret(clientset, namespace, opts.gitBasicAuthSecret(p).Name); err != nil {
+			return err
+		}
+	}
+
+	//the namespace takes the Drone server and the secrets in it along
+	if giteaUsers.ParticipantNamespaces != nil {
+		if err := deleteParticipantNamespace(clientset, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//deleteSecret deletes the secret if it exists, only when it was created by the helper
+func deleteSecret(clientset kubernetes.Interface, namespace, name string) error {
+	secrets := clientset.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !isManagedSecret(secret) {
+		log.Warnf("Leaving Kubernetes secret %s/%s as it is not labelled %s=%s", namespace, name, managedByLabel, managedBy)
+		return nil
+	}
+	//the precondition keeps a secret recreated in the meantime
+	if err := secrets.Delete(context.TODO(), name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &secret.UID}}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	log.Infof("Deleted Kubernetes secret %s/%s", namespace, name)
+	return nil
+}
+
+//deleteParticipantNamespace deletes the namespace of the participant, only when it was created for the participant
+func deleteParticipantNamespace(clientset kubernetes.Interface, p *Participant) error {
+	namespaces := clientset.CoreV1().Namespaces()
+	ns, err := namespaces.Get(context.TODO(), p.Namespace, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !isParticipantNamespace(ns, p) {
+		log.Warnf("Keeping the namespace %s of %s, it is not labelled %s=%s", p.Namespace, p.UserName, participantLabel, p.UserName)
+		return nil
+	}
+	if err := namespaces.Delete(context.TODO(), p.Namespace, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	log.Infof("Deleted Kubernetes namespace %s", p.Namespace)
+	return nil
+}
+
+//deleteUserRepos deletes the repos owned by the user
+func deleteUserRepos(c *gitea.Client, userName string) error {
+	var repos []*gitea.Repository
+	for page := 1; ; page++ {
+		r, resp, err := c.ListUserRepos(userName, gitea.ListReposOptions{ListOptions: gitea.ListOptions{Page: page, PageSize: 50}})
+		if err != nil {
+			if isNotFound(resp) {
+				return nil
+			}
+			return err
+		}
+		repos = append(repos, r...)
+		if len(r) < 50 {
+			break
+		}
+	}
+	for _, r := range repos {
+		//the repos of the organizations the user is a member of are listed too
+		if r.Owner != nil && r.Owner.UserName != userName {
+			continue
+		}
+		if resp, err := c.DeleteRepo(userName, r.Name); err != nil && !isNotFound(resp) {
+			return err
+		}
+		log.Infof("Deleted repo %s/%s", userName, r.Name)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+//workshopGVR is the resource of the Workshop custom resources, whose spec is the workshop configuration
+var workshopGVR = schema.GroupVersionResource{
+	Group:    "drone-workshop.kameshsampath.github.io",
+	Version:  "v1alpha1",
+	Resource: "workshops",
+}
+
+//workshopFinalizer holds the deletion of a Workshop until its participants are torn down
+const workshopFinalizer = "drone-workshop.kameshsampath.github.io/teardown"
+
+//workshopForceTeardown is the annotation removing the finalizer of a Workshop whose teardown fails
+const workshopForceTeardown = "drone-workshop.kameshsampath.github.io/force-teardown"
+
+//workshopReady is the condition of the Workshop that is true when all its participants are provisioned
+const workshopReady = "Ready"
+
+//WorkshopStatus is the status of a Workshop custom resource
+type WorkshopStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	//Participants records the participants provisioned by the workshop, torn down when they are removed from it
+	Participants []WorkshopParticipantStatus `json:"participants,omitempty"`
+}
+
+//WorkshopParticipantStatus is the status of a participant of a Workshop
+type WorkshopParticipantStatus struct {
+	UserName  string `json:"userName"`
+	Namespace string `json:"namespace,omitempty"`
+	Ready     bool   `json:"ready"`
+	//Provisioned is true when the workshop created the Gitea user, only these are torn down
+	Provisioned bool `json:"provisioned"`
+	//Repos are the clone URLs of the participant repos
+	Repos []string `json:"repos,omitempty"`
+	//Message is why the participant is not ready
+	Message string `json:"message,omitempty"`
+}
+
+//WorkshopSecretRef is the key of a secret in the namespace of the Workshop
+type WorkshopSecretRef struct {
+	Name string `json:"name"`
+	//Key defaults to password
+	Key string `json:"key,omitempty"`
+}
+
+//workshopSpec returns the validated workshop configuration of the spec of the Workshop,
+//the Gitea admin password is read from the secret the spec refers to
+func workshopSpec(clientset kubernetes.Interface, u *unstructured.Unstructured) (*WorkshopOptions, error) {
+	spec, ok, err := unstructured.NestedMap(u.Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("the Workshop %s/%s has no spec", u.GetNamespace(), u.GetName())
+	}
+	//anyone reading the Workshop would read the password
+	if _, ok := spec["giteaAdminUserPassword"]; ok {
+		return nil, fmt.Errorf("the Workshop %s/%s has the giteaAdminUserPassword, set giteaAdminUserPasswordSecretRef instead", u.GetNamespace(), u.GetName())
+	}
+	var ref WorkshopSecretRef
+	m, ok, err := unstructured.NestedMap(spec, "giteaAdminUserPasswordSecretRef")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("the Workshop %s/%s has no giteaAdminUserPasswordSecretRef", u.GetNamespace(), u.GetName())
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &ref); err != nil {
+		return nil, err
+	}
+	delete(spec, "giteaAdminUserPasswordSecretRef")
+
+	//the spec has the keys of the workshop configuration file
+	b, err := yamlv2.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	workshopOpts, err := parseWorkshopOptions(b)
+	if err != nil {
+		return nil, err
+	}
+	if workshopOpts.GiteaAdminPassword, err = ref.value(clientset, u.GetNamespace()); err != nil {
+		return nil, err
+	}
+	return workshopOpts, nil
+}
+
+//value returns the value of the key of the secret
+func (ref WorkshopSecretRef) value(clientset kubernetes.Interface, namespace string) (string, error) {
+	key := ref.Key
+	if key == "" {
+		key = "password"
+	}
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	v, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("the secret %s/%s has no key %s", namespace, ref.Name, key)
+	}
+	return string(v), nil
+}
+
+//validateSecretNamespaces checks the Workshop writes its secrets only to its own namespace, to the participant
+//namespaces or to the allowed namespaces, so that a Workshop can't have the controller overwrite the secrets
+//of any namespace
+func (opts *WorkshopOptions) validateSecretNamespaces(namespace string, allowed []string) error {
+	permissions, err := opts.namespacePermissions()
+	if err != nil {
+		return err
+	}
+	var refused []string
+	for ns, np := range permissions {
+		if !np.secrets || np.participant || ns == namespace || stringIn(allowed, ns) {
+			continue
+		}
+		refused = append(refused, ns)
+	}
+	if len(refused) > 0 {
+		sort.Strings(refused)
+		return fmt.Errorf("the secrets of the Workshop can't be written to the namespaces %s, only to the namespace %s of the Workshop, the participant namespaces and the namespaces the controller allows with --secret-namespaces", strings.Join(refused, ","), namespace)
+	}
+	return nil
+}
+
+//stringIn reports whether the values have the value
+func stringIn(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//workshopStatus returns the status of the Workshop
+func workshopStatus(u *unstructured.Unstructured) (*WorkshopStatus, error) {
+	status := &WorkshopStatus{}
+	m, ok, err := unstructured.NestedMap(u.Object, "status")
+	if err != nil || !ok {
+		return status, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+//setWorkshopStatus sets the status of the Workshop, true when it changed
+func setWorkshopStatus(u *unstructured.Unstructured, status *WorkshopStatus) (bool, error) {
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return false, err
+	}
+	if existing, ok, _ := unstructured.NestedMap(u.Object, "status"); ok && equality.Semantic.DeepEqual(existing, m) {
+		return false, nil
+	}
+	return true, unstructured.SetNestedMap(u.Object, m, "status")
+}
+
+//setReady sets the Ready condition of the status
+func (s *WorkshopStatus) setReady(ready bool, reason, message string, generation int64) {
+	status := metav1.ConditionFalse
+	if ready {
+		status = metav1.ConditionTrue
+	}
+	s.ObservedGeneration = generation
+	meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:               workshopReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+//hasFinalizer is true when the Workshop has the teardown finalizer
+func hasFinalizer(u *unstructured.Unstructured) bool {
+	for _, f := range u.GetFinalizers() {
+		if f == workshopFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+//forceTeardown is true when the Workshop is annotated to be removed even if its teardown fails
+func forceTeardown(u *unstructured.Unstructured) bool {
+	return u.GetAnnotations()[workshopForceTeardown] == "true"
+}
+
+//removeFinalizer removes the teardown finalizer of the Workshop
+func removeFinalizer(u *unstructured.Unstructured) {
+	var finalizers []string
+	for _, f := range u.GetFinalizers() {
+		if f != workshopFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	u.SetFinalizers(finalizers)
+}